@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	"log"
+	"os"
+	"time"
+)
+
+// this accountCleanup worker will run alongside the main REST API service
+// every tick it deletes user_invitations that expired more than the grace period ago
+// and unverified accounts older than the grace period that have no pending invitation
+// (registrations that were never activated), both are single DELETE statements so
+// more than one worker process can run against the same database.
+// it also purges the accounts whose scheduled deletion (DELETE /api/me) is due, each
+// purge locks the user row so concurrent workers skip users already purged.
+// sessions that were revoked or expired are kept for the session retention period and then deleted
+// (with their refresh tokens), rotated refresh tokens are kept until they expire so that a replay is
+// detected for as long as the token could have been used. expired refresh tokens, sign-in links and
+// 2fa challenges are deleted right away
+
+const (
+	DEFAULT_CLEANUP_INTERVAL        = time.Hour
+	DEFAULT_UNVERIFIED_GRACE_PERIOD = 72 * time.Hour
+	DEFAULT_SESSION_RETENTION       = 7 * 24 * time.Hour
+	USER_DELETION_BATCH_SIZE        = 100
+)
+
+type config struct {
+	dbConnStr        string
+	interval         time.Duration
+	gracePeriod      time.Duration
+	sessionRetention time.Duration
+}
+
+func loadConfig() (*config, error) {
+
+	godotenv.Load()
+
+	dbConnStr := os.Getenv("POSTGRES_DB_CONN")
+	if dbConnStr == "" {
+		return nil, errors.New("POSTGRES_DB_CONN env variable not set")
+	}
+
+	interval, err := durationFromEnv("CLEANUP_INTERVAL", DEFAULT_CLEANUP_INTERVAL)
+	if err != nil {
+		return nil, err
+	}
+
+	gracePeriod, err := durationFromEnv("UNVERIFIED_ACCOUNT_GRACE_PERIOD", DEFAULT_UNVERIFIED_GRACE_PERIOD)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionRetention, err := durationFromEnv("SESSION_RETENTION", DEFAULT_SESSION_RETENTION)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config{
+		dbConnStr:        dbConnStr,
+		interval:         interval,
+		gracePeriod:      gracePeriod,
+		sessionRetention: sessionRetention,
+	}, nil
+}
+
+// durationFromEnv go duration strings, e.g. "30m", "72h"
+func durationFromEnv(key string, defaultValue time.Duration) (time.Duration, error) {
+
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid %s env variable %q, expected a positive duration like 72h", key, value)
+	}
+
+	return duration, nil
+}
+
+func main() {
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := connectToPostgresDb(cfg.dbConnStr)
+	if err != nil {
+		log.Fatalf("Error connecting to postgres db: %v\n", err)
+	}
+	defer db.Close()
+
+	storage := storage.NewStorage(db)
+
+	log.Printf("account cleanup started, running every %v with a grace period of %v\n", cfg.interval, cfg.gracePeriod)
+
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		cleanUpAbandonedRegistrations(storage, cfg.gracePeriod)
+		purgeDeletedUsers(storage)
+		pruneSessions(storage, cfg.sessionRetention)
+		pruneExpiredLoginTokens(storage)
+		<-ticker.C
+	}
+}
+
+func cleanUpAbandonedRegistrations(s *storage.Storage, gracePeriod time.Duration) {
+
+	cutOff := time.Now().Add(-gracePeriod)
+
+	deletedInvitations, err := s.DeleteExpiredUserInvitations(cutOff)
+	if err != nil {
+		log.Printf("failed to delete expired user invitations: %v\n", err)
+	} else if deletedInvitations > 0 {
+		log.Printf("deleted %d expired user invitations\n", deletedInvitations)
+	}
+
+	deletedUsers, err := s.DeleteAbandonedUnverifiedUsers(cutOff)
+	if err != nil {
+		log.Printf("failed to delete abandoned unverified users: %v\n", err)
+	} else if deletedUsers > 0 {
+		log.Printf("deleted %d abandoned unverified users\n", deletedUsers)
+	}
+}
+
+func purgeDeletedUsers(s *storage.Storage) {
+
+	var purgedUsers int
+
+	for {
+		userIds, err := s.GetUserIdsDueForDeletion(time.Now(), USER_DELETION_BATCH_SIZE)
+		if err != nil {
+			log.Printf("failed to get users due for deletion: %v\n", err)
+			return
+		}
+
+		for _, userId := range userIds {
+			isPurged, err := s.PurgeUser(userId)
+			if err != nil {
+				//	retried on the next tick, stop so that a failing user is not picked up again in this loop
+				log.Printf("failed to purge user %d: %v\n", userId, err)
+				return
+			}
+			if isPurged {
+				purgedUsers++
+			}
+		}
+
+		if len(userIds) < USER_DELETION_BATCH_SIZE {
+			break
+		}
+	}
+
+	if purgedUsers > 0 {
+		log.Printf("purged %d deleted users\n", purgedUsers)
+	}
+}
+
+func pruneSessions(s *storage.Storage, retention time.Duration) {
+
+	cutOff := time.Now().Add(-retention)
+
+	deletedSessions, err := s.DeleteEndedUserSessions(cutOff)
+	if err != nil {
+		log.Printf("failed to delete ended user sessions: %v\n", err)
+	} else if deletedSessions > 0 {
+		log.Printf("deleted %d ended user sessions\n", deletedSessions)
+	}
+
+	deletedRefreshTokens, err := s.DeleteExpiredRefreshTokens(time.Now())
+	if err != nil {
+		log.Printf("failed to delete expired refresh tokens: %v\n", err)
+	} else if deletedRefreshTokens > 0 {
+		log.Printf("deleted %d expired refresh tokens\n", deletedRefreshTokens)
+	}
+}
+
+// pruneExpiredLoginTokens short lived tokens of the login flows, expired ones can never be used again
+func pruneExpiredLoginTokens(s *storage.Storage) {
+
+	now := time.Now()
+
+	deletedMagicLinks, err := s.DeleteExpiredMagicLinks(now)
+	if err != nil {
+		log.Printf("failed to delete expired magic links: %v\n", err)
+	} else if deletedMagicLinks > 0 {
+		log.Printf("deleted %d expired magic links\n", deletedMagicLinks)
+	}
+
+	deletedChallenges, err := s.DeleteExpiredTwoFactorChallenges(now)
+	if err != nil {
+		log.Printf("failed to delete expired two factor challenges: %v\n", err)
+	} else if deletedChallenges > 0 {
+		log.Printf("deleted %d expired two factor challenges\n", deletedChallenges)
+	}
+}
+
+func connectToPostgresDb(dbConnStr string) (*sqlx.DB, error) {
+
+	db, err := sqlx.Open("postgres", dbConnStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.Ping(); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -35,26 +35,65 @@ func (s *server) mount() *chi.Mux {
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/register", s.handler.RegisterUserHandler)
 			r.Put("/activate/{token}", s.handler.ActivateUserHandler)
+			r.Post("/resend-activation", s.handler.ResendActivationHandler)
 			r.Post("/login", s.handler.LoginUserHandler)
+			r.Post("/login/2fa", s.handler.LoginTwoFactorHandler)
+			r.Post("/forgot-password", s.handler.ForgotPasswordHandler)
+			r.Put("/reset-password/{token}", s.handler.ResetPasswordHandler)
+			r.Post("/magic-link", s.handler.MagicLinkHandler)
+			r.Put("/magic-link/{token}", s.handler.ConsumeMagicLinkHandler)
+			r.Put("/confirm-email/{token}", s.handler.ConfirmEmailChangeHandler)
+			r.Post("/refresh", s.handler.RefreshTokenHandler)
+			r.With(s.handler.OptionalAuthMiddleware).Post("/logout", s.handler.LogoutHandler)
+			r.With(s.handler.AuthMiddleware).Post("/logout-all", s.handler.LogoutEverywhereHandler)
 			r.With(s.handler.AuthMiddleware).Get("/user", s.handler.GetUserHandler)
+
+			r.Route("/oauth", func(r chi.Router) {
+				r.Get("/providers", s.handler.GetOAuthProvidersHandler)
+				r.Get("/{provider}/login", s.handler.OAuthLoginHandler)
+				r.Get("/{provider}/callback", s.handler.OAuthCallbackHandler)
+			})
 		})
 
 		r.Route("/blog", func(r chi.Router) {
 			r.Group(func(r chi.Router) {
+				r.Use(s.handler.AllowTokenScope(handlers.TokenScopeBlogsWrite))
 				r.Use(s.handler.AuthMiddleware)
 				r.Post("/", s.handler.CreateBlogHandler)
 			})
 
 			r.Route("/{blogId}", func(r chi.Router) {
+				//	a blogs:write token reads its own blogs (and their ETags) without the read scope
+				r.With(s.handler.AllowTokenScope(handlers.TokenScopeRead, handlers.TokenScopeBlogsWrite), s.handler.OptionalAuthMiddleware).Get("/", s.handler.GetBlogHandler)
+
 				r.Group(func(r chi.Router) {
+					r.Use(s.handler.AllowTokenScope(handlers.TokenScopeBlogsWrite))
 					r.Use(s.handler.AuthMiddleware)
 					r.Delete("/", s.handler.DeleteBlogHandler)
+					r.Patch("/", s.handler.UpdateBlogHandler)
 					r.Patch("/status", s.handler.UpdateBlogStatusHandler)
+					r.Put("/schedule", s.handler.ScheduleBlogHandler)
+					r.Delete("/schedule", s.handler.CancelBlogScheduleHandler)
+					r.Post("/revisions/{revisionNumber}/restore", s.handler.RestoreBlogRevisionHandler)
+				})
+
+				r.Group(func(r chi.Router) {
+					r.Use(s.handler.AuthMiddleware)
+					r.Use(s.handler.RequirePermission(handlers.PermissionBlogFeature))
+					r.Put("/feature", s.handler.FeatureBlogHandler)
+					r.Delete("/feature", s.handler.UnfeatureBlogHandler)
+				})
+
+				r.Group(func(r chi.Router) {
+					r.Use(s.handler.AuthMiddleware)
+					r.Get("/revisions", s.handler.GetBlogRevisionsHandler)
+					r.Get("/revisions/{revisionNumber}", s.handler.GetBlogRevisionHandler)
 					r.Post("/like", s.handler.LikeBlogHandler)
 					r.Post("/bookmark", s.handler.BookmarkBlogHandler)
 				})
 
 				r.Route("/blog-comment", func(r chi.Router) {
+					r.Use(s.handler.AllowTokenScope(handlers.TokenScopeCommentsWrite))
 					r.Use(s.handler.AuthMiddleware)
 					r.Post("/", s.handler.CreateBlogCommentHandler)                   // fixed
 					r.Delete("/{blogCommentId}", s.handler.DeleteBlogCommentHandler)  // fixed
@@ -69,6 +108,8 @@ func (s *server) mount() *chi.Mux {
 			//	get blog posts feed for a topic handler - unauthenticated
 			r.Get("/{topicId}/blogs", s.handler.GetBlogsFeedByTopicHandler)
 			r.With(s.handler.OptionalAuthMiddleware).Get("/blogs/feed", s.handler.GetBlogsFeedHandler)
+			r.Get("/search", s.handler.SearchBlogsHandler)
+			r.Get("/featured", s.handler.GetFeaturedBlogsHandler)
 		})
 
 		r.Route("/topic", func(r chi.Router) {
@@ -76,9 +117,9 @@ func (s *server) mount() *chi.Mux {
 			r.Get("/topics", s.handler.GetTopicsHandler)
 
 			r.Group(func(r chi.Router) {
-				//	add , delete and edit blog topics (admin routes)
+				//	add , delete and edit blog topics (staff routes)
 				r.Use(s.handler.AuthMiddleware)
-				r.Use(s.handler.AdminAuthMiddleware)
+				r.Use(s.handler.RequirePermission(handlers.PermissionTopicManage))
 				r.Post("/", s.handler.CreateTopicHandler)
 				r.Put("/{topicId}", s.handler.UpdateTopicHandler)
 				r.Delete("/{topicId}", s.handler.DeleteTopicHandler)
@@ -90,6 +131,78 @@ func (s *server) mount() *chi.Mux {
 			})
 		})
 
+		r.Route("/me", func(r chi.Router) {
+			r.Use(s.handler.AuthMiddleware)
+
+			r.Group(func(r chi.Router) {
+				//	account management, never available to personal access tokens
+				r.Use(s.handler.RequireLoginSession)
+				r.Patch("/", s.handler.UpdateMeHandler)
+				r.Get("/sessions", s.handler.GetMySessionsHandler)
+				r.Delete("/sessions/{sessionId}", s.handler.RevokeMySessionHandler)
+				r.Get("/tokens", s.handler.GetMyTokensHandler)
+				r.Post("/tokens", s.handler.CreateMyTokenHandler)
+				r.Delete("/tokens/{tokenId}", s.handler.RevokeMyTokenHandler)
+				r.Get("/2fa", s.handler.GetMyTwoFactorHandler)
+				r.Post("/2fa/enroll", s.handler.EnrollTwoFactorHandler)
+				r.Post("/2fa/confirm", s.handler.ConfirmTwoFactorHandler)
+				r.Post("/2fa/disable", s.handler.DisableTwoFactorHandler)
+				r.Post("/2fa/recovery-codes", s.handler.RegenerateRecoveryCodesHandler)
+				r.Get("/identities", s.handler.GetMyIdentitiesHandler)
+				r.Post("/identities/{provider}", s.handler.LinkIdentityHandler)
+				r.Delete("/identities/{identityId}", s.handler.UnlinkIdentityHandler)
+				r.Post("/email", s.handler.ChangeEmailHandler)
+				r.Delete("/", s.handler.DeleteMeHandler)
+				r.Get("/export", s.handler.ExportMeHandler)
+			})
+
+			r.Get("/bookmarks", s.handler.GetMyBookmarksHandler)
+			r.Put("/bookmarks/{blogId}/folder", s.handler.MoveBookmarkHandler)
+			r.Get("/likes", s.handler.GetMyLikesHandler)
+			r.Get("/bookmark-folders", s.handler.GetMyBookmarkFoldersHandler)
+			r.Post("/bookmark-folders", s.handler.CreateBookmarkFolderHandler)
+			r.Put("/bookmark-folders/{bookmarkFolderId}", s.handler.UpdateBookmarkFolderHandler)
+			r.Delete("/bookmark-folders/{bookmarkFolderId}", s.handler.DeleteBookmarkFolderHandler)
+		})
+
+		r.Route("/admin/users", func(r chi.Router) {
+			r.Use(s.handler.AuthMiddleware)
+
+			r.Group(func(r chi.Router) {
+				r.Use(s.handler.RequirePermission(handlers.PermissionUserManage))
+				r.Get("/", s.handler.GetAdminUsersHandler)
+				r.Get("/{userId}", s.handler.GetAdminUserHandler)
+				r.Put("/{userId}/role", s.handler.UpdateUserRoleHandler)
+				r.Post("/{userId}/verify", s.handler.VerifyUserHandler)
+				r.Post("/{userId}/password-reset", s.handler.TriggerPasswordResetHandler)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(s.handler.RequirePermission(handlers.PermissionUserBan))
+				r.Post("/{userId}/suspend", s.handler.SuspendUserHandler)
+				r.Post("/{userId}/ban", s.handler.BanUserHandler)
+				r.Delete("/{userId}/suspension", s.handler.UnsuspendUserHandler)
+			})
+		})
+
+		r.Route("/moderation", func(r chi.Router) {
+			r.Use(s.handler.AuthMiddleware)
+			r.With(s.handler.RequirePermission(handlers.PermissionCommentModerate)).Delete("/blog-comments/{blogCommentId}", s.handler.ModerateDeleteBlogCommentHandler)
+		})
+
+		r.Route("/users", func(r chi.Router) {
+
+			r.Get("/{userId}", s.handler.GetUserProfileHandler)
+			r.With(s.handler.OptionalAuthMiddleware).Get("/{userId}/blogs", s.handler.GetUserBlogsHandler)
+			r.Get("/{userId}/followers", s.handler.GetUserFollowersHandler)
+			r.Get("/{userId}/following", s.handler.GetUserFollowingHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(s.handler.AuthMiddleware)
+				r.Post("/{userId}/follow", s.handler.FollowUserHandler)
+			})
+		})
+
 		r.Route("/file", func(r chi.Router) {
 			r.Post("/upload", s.handler.UploadImageFileHandler)
 		})
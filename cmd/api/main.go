@@ -2,11 +2,14 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"github.com/dhruv15803/go-blog-app/internal/handlers"
 	"github.com/dhruv15803/go-blog-app/internal/storage"
 	"github.com/joho/godotenv"
 	"log"
+	"net/netip"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -28,13 +31,20 @@ type cloudinaryConfig struct {
 }
 
 type config struct {
-	addr                string
-	readRequestTimeout  time.Duration
-	writeRequestTimeout time.Duration
-	clientUrl           string
-	dbConfig            dbConfig
-	redisConfig         redisConfig
-	cloudinaryConfig    cloudinaryConfig
+	addr                  string
+	readRequestTimeout    time.Duration
+	writeRequestTimeout   time.Duration
+	clientUrl             string
+	requireAdminTwoFactor bool
+	// email users whose account got locked out by failed logins
+	sendSuspiciousLoginEmails bool
+	// the forwarded client ip header is only trusted from these proxies
+	clientIp handlers.ClientIpConfig
+	// off by default, behind a proxy without TRUSTED_PROXIES every client shares the proxy's address
+	throttleLoginsByIp bool
+	dbConfig           dbConfig
+	redisConfig        redisConfig
+	cloudinaryConfig   cloudinaryConfig
 }
 
 func loadConfig() (*config, error) {
@@ -47,6 +57,10 @@ func loadConfig() (*config, error) {
 	redisPassword := os.Getenv("REDIS_PASSWORD")
 	clientUrl := os.Getenv("CLIENT_URL")
 	cloudinaryUrl := os.Getenv("CLOUDINARY_URL")
+	requireAdminTwoFactor := os.Getenv("REQUIRE_ADMIN_2FA") == "true"
+	sendSuspiciousLoginEmails := os.Getenv("SUSPICIOUS_LOGIN_EMAILS") == "true"
+	throttleLoginsByIp := os.Getenv("LOGIN_IP_THROTTLE") == "true"
+	clientIpHeader := strings.TrimSpace(os.Getenv("CLIENT_IP_HEADER"))
 	if port == "" || dbConnStr == "" {
 		return nil, errors.New("PORT or POSTGRES_DB_CONN not set")
 	}
@@ -57,11 +71,26 @@ func loadConfig() (*config, error) {
 		return nil, errors.New("CLOUDINARY_URL not set")
 	}
 
+	trustedProxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, err
+	}
+	if clientIpHeader != "" && len(trustedProxies) == 0 {
+		return nil, errors.New("CLIENT_IP_HEADER set without TRUSTED_PROXIES")
+	}
+
 	cfg := &config{
-		addr:                port,
-		readRequestTimeout:  time.Second * 15,
-		writeRequestTimeout: time.Second * 15,
-		clientUrl:           clientUrl,
+		addr:                      port,
+		readRequestTimeout:        time.Second * 15,
+		writeRequestTimeout:       time.Second * 15,
+		clientUrl:                 clientUrl,
+		requireAdminTwoFactor:     requireAdminTwoFactor,
+		sendSuspiciousLoginEmails: sendSuspiciousLoginEmails,
+		clientIp: handlers.ClientIpConfig{
+			TrustedProxies:  trustedProxies,
+			ForwardedHeader: clientIpHeader,
+		},
+		throttleLoginsByIp: throttleLoginsByIp,
 		dbConfig: dbConfig{
 			dbConnStr:       dbConnStr,
 			maxOpenConns:    50,
@@ -81,6 +110,38 @@ func loadConfig() (*config, error) {
 	return cfg, nil
 }
 
+// parseTrustedProxies comma separated ip addresses and CIDR ranges, e.g. "10.0.0.0/8,192.168.1.10"
+func parseTrustedProxies(value string) ([]netip.Prefix, error) {
+
+	var trustedProxies []netip.Prefix
+
+	for _, proxy := range strings.Split(value, ",") {
+
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: %w", proxy, err)
+			}
+			trustedProxies = append(trustedProxies, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: %w", proxy, err)
+		}
+		addr = addr.Unmap()
+		trustedProxies = append(trustedProxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return trustedProxies, nil
+}
+
 func main() {
 
 	cfg, err := loadConfig()
@@ -106,9 +167,14 @@ func main() {
 		log.Fatalf("Error creating cloudinary client: %v\n", err)
 	}
 
+	oauthProviders, err := loadOAuthProviders()
+	if err != nil {
+		log.Fatalf("Error loading oauth providers: %v\n", err)
+	}
+
 	//layers
 	storage := storage.NewStorage(db)
-	handler := handlers.NewHandler(storage, redisClient, cld, cfg.clientUrl)
+	handler := handlers.NewHandler(storage, redisClient, cld, cfg.clientUrl, cfg.requireAdminTwoFactor, oauthProviders, cfg.sendSuspiciousLoginEmails, cfg.clientIp, cfg.throttleLoginsByIp)
 
 	server := newServer(cfg.addr, cfg.readRequestTimeout, cfg.writeRequestTimeout, handler)
 
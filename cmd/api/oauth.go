@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dhruv15803/go-blog-app/internal/oauth"
+	"os"
+	"strings"
+)
+
+// loadOAuthProviders reads the providers listed in OAUTH_PROVIDERS (e.g. "google,github,mock"),
+// each configured with OAUTH_<NAME>_CLIENT_ID, OAUTH_<NAME>_CLIENT_SECRET, OAUTH_<NAME>_ISSUER_URL (oidc),
+// OAUTH_<NAME>_TYPE ("oidc" or "github") and OAUTH_<NAME>_SCOPES
+func loadOAuthProviders() (map[string]oauth.Provider, error) {
+
+	providers := make(map[string]oauth.Provider)
+
+	providerNames := strings.TrimSpace(os.Getenv("OAUTH_PROVIDERS"))
+	if providerNames == "" {
+		return providers, nil
+	}
+
+	redirectBaseUrl := strings.TrimSuffix(os.Getenv("OAUTH_REDIRECT_BASE_URL"), "/")
+	if redirectBaseUrl == "" {
+		return nil, errors.New("OAUTH_REDIRECT_BASE_URL not set")
+	}
+
+	for _, providerName := range strings.Split(providerNames, ",") {
+
+		providerName = strings.ToLower(strings.TrimSpace(providerName))
+		if providerName == "" {
+			continue
+		}
+
+		envPrefix := "OAUTH_" + strings.ToUpper(strings.ReplaceAll(providerName, "-", "_")) + "_"
+
+		providerType := os.Getenv(envPrefix + "TYPE")
+		if providerType == "" {
+			providerType = oauth.ProviderTypeOIDC
+			if providerName == "github" {
+				providerType = oauth.ProviderTypeGitHub
+			}
+		}
+
+		issuerUrl := os.Getenv(envPrefix + "ISSUER_URL")
+		if issuerUrl == "" && providerName == "google" {
+			issuerUrl = "https://accounts.google.com"
+		}
+
+		provider, err := oauth.NewProvider(oauth.ProviderConfig{
+			Name:         providerName,
+			Type:         providerType,
+			IssuerUrl:    issuerUrl,
+			ClientId:     os.Getenv(envPrefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(envPrefix + "CLIENT_SECRET"),
+			RedirectUrl:  fmt.Sprintf("%s/api/auth/oauth/%s/callback", redirectBaseUrl, providerName),
+			Scopes:       strings.FieldsFunc(os.Getenv(envPrefix+"SCOPES"), func(r rune) bool { return r == ',' || r == ' ' }),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		providers[providerName] = provider
+	}
+
+	return providers, nil
+}
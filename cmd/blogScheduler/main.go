@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	"log"
+	"os"
+	"time"
+)
+
+// this blogScheduler will run alongside the main REST API service
+// every tick it publishes the scheduled blogs whose publish_at has passed,
+// publishing is a single conditional UPDATE so more than one scheduler
+// process can run against the same database without publishing a blog twice
+
+const (
+	SCHEDULER_TICK_INTERVAL = 30 * time.Second
+)
+
+type dbConfig struct {
+	dbConnStr string
+}
+
+func loadPostgresDbConfig() (*dbConfig, error) {
+
+	godotenv.Load()
+
+	dbConnStr := os.Getenv("POSTGRES_DB_CONN")
+	if dbConnStr == "" {
+		return nil, errors.New("POSTGRES_DB_CONN env variable not set")
+	}
+
+	return &dbConfig{
+		dbConnStr: dbConnStr,
+	}, nil
+}
+
+func main() {
+	cfg, err := loadPostgresDbConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := connectToPostgresDb(cfg.dbConnStr)
+	if err != nil {
+		log.Fatalf("Error connecting to postgres db: %v\n", err)
+	}
+	defer db.Close()
+
+	storage := storage.NewStorage(db)
+
+	log.Printf("blog scheduler started, checking every %v\n", SCHEDULER_TICK_INTERVAL)
+
+	ticker := time.NewTicker(SCHEDULER_TICK_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		publishDueBlogs(storage)
+		<-ticker.C
+	}
+}
+
+func publishDueBlogs(s *storage.Storage) {
+
+	now := time.Now()
+
+	publishedBlogs, err := s.PublishDueScheduledBlogs(now)
+	if err != nil {
+		log.Printf("failed to publish scheduled blogs: %v\n", err)
+	}
+	for _, blog := range publishedBlogs {
+		log.Printf("published scheduled blog %d\n", blog.Id)
+	}
+
+	//	due blogs left without topics (topics deleted after scheduling) cannot be published
+	revertedBlogs, err := s.RevertUnpublishableScheduledBlogs(now)
+	if err != nil {
+		log.Printf("failed to revert unpublishable scheduled blogs: %v\n", err)
+	}
+	for _, blog := range revertedBlogs {
+		log.Printf("scheduled blog %d has no topics, moved back to draft\n", blog.Id)
+	}
+}
+
+func connectToPostgresDb(dbConnStr string) (*sqlx.DB, error) {
+
+	db, err := sqlx.Open("postgres", dbConnStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.Ping(); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/dhruv15803/go-blog-app/scripts"
+	"strconv"
+)
+
+var blogHeaders = []string{"ID", "TITLE", "STATUS", "AUTHOR ID", "VERSION"}
+
+func blogRow(blog storage.Blog) []string {
+	return []string{strconv.Itoa(blog.Id), blog.BlogTitle, string(blog.BlogStatus), strconv.Itoa(blog.BlogAuthorId), strconv.Itoa(blog.BlogVersion)}
+}
+
+func runBlogsArchive(args []string) (action, error) {
+
+	fs, output := newFlagSet("blogs archive")
+	blogId := fs.Int("id", 0, "id of a published blog")
+	if err := parseFlags(fs, output, args); err != nil {
+		return nil, err
+	}
+
+	if *blogId <= 0 {
+		return nil, errors.New("-id is required")
+	}
+
+	return func(s *scripts.Script) error {
+		blog, err := s.ArchiveBlog(*blogId)
+		if err != nil {
+			return err
+		}
+
+		return writeOutput(*output, blog, blogHeaders, [][]string{blogRow(blog.Blog)})
+	}, nil
+}
+
+func runBlogsDelete(args []string) (action, error) {
+
+	fs, output := newFlagSet("blogs delete")
+	blogId := fs.Int("id", 0, "id of the blog")
+	if err := parseFlags(fs, output, args); err != nil {
+		return nil, err
+	}
+
+	if *blogId <= 0 {
+		return nil, errors.New("-id is required")
+	}
+
+	return func(s *scripts.Script) error {
+		blog, err := s.DeleteBlog(*blogId)
+		if err != nil {
+			return err
+		}
+
+		return writeOutput(*output, blog, blogHeaders, [][]string{blogRow(*blog)})
+	}, nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"flag"
+	"fmt"
 	"github.com/dhruv15803/go-blog-app/internal/storage"
 	"github.com/dhruv15803/go-blog-app/scripts"
 	"github.com/jmoiron/sqlx"
@@ -10,13 +11,63 @@ import (
 	_ "github.com/lib/pq"
 	"log"
 	"os"
+	"strings"
 )
 
+// admin cli for the go-blog-app, every subcommand goes through internal/storage (via scripts)
+//
+//	createUser users create -email <email> -password <password> [-role user|admin|moderator|editor]
+//	createUser users list [-q <email or username>] [-role <role>] [-verified true|false] [-suspended true|false] [-page 1] [-limit 20]
+//	createUser users promote -email <email> [-role admin]
+//	createUser users demote -email <email>
+//	createUser users disable -email <email> [-reason <reason>]
+//	createUser users enable -email <email>
+//	createUser users reset-password -email <email> -password <password>
+//	createUser topics export [-file topics.json]
+//	createUser topics import -file topics.json
+//	createUser blogs archive -id <blogId>
+//	createUser blogs delete -id <blogId>
+//
+// every subcommand takes -output table|json (table by default)
+
 type dbConfig struct {
 	dbConnStr string
 }
 
-// executable to create a user for the go-blog-app
+// command parses the flags that follow the subcommand's name, the returned action
+// runs once the database is connected so that usage errors and -h never need it
+type command func(args []string) (action, error)
+
+type action func(s *scripts.Script) error
+
+var commands = map[string]map[string]command{
+	"users": {
+		"create":         runUsersCreate,
+		"list":           runUsersList,
+		"promote":        runUsersPromote,
+		"demote":         runUsersDemote,
+		"disable":        runUsersDisable,
+		"enable":         runUsersEnable,
+		"reset-password": runUsersResetPassword,
+	},
+	"topics": {
+		"export": runTopicsExport,
+		"import": runTopicsImport,
+	},
+	"blogs": {
+		"archive": runBlogsArchive,
+		"delete":  runBlogsDelete,
+	},
+}
+
+const usage = `usage: createUser <group> <command> [flags]
+
+  users   create | list | promote | demote | disable | enable | reset-password
+  topics  export | import
+  blogs   archive | delete
+
+run "createUser <group> <command> -h" for the flags of a command`
+
 func loadPostgresDbConfig() (*dbConfig, error) {
 
 	godotenv.Load()
@@ -32,6 +83,34 @@ func loadPostgresDbConfig() (*dbConfig, error) {
 }
 
 func main() {
+
+	args := os.Args[1:]
+
+	//	"createUser -email ... -password ..." from before the subcommands still creates a user
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "-help" {
+		args = append([]string{"users", "create"}, args...)
+	}
+
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[args[0]][args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", strings.Join(args[:2], " "), usage)
+		os.Exit(2)
+	}
+
+	run, err := cmd(args[2:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", args[0], args[1], err)
+		os.Exit(2)
+	}
+
 	cfg, err := loadPostgresDbConfig()
 	if err != nil {
 		log.Fatal(err)
@@ -42,26 +121,16 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error connecting to postgres db: %v\n", err)
 	}
-	//	create a storage layer instance
-	storage := storage.NewStorage(db)
-	//	pass storage layer instance to scripts instance
-	scripts := scripts.NewScript(storage)
-
-	//	get the email and plain text password from the CLI args
-	emailPtr := flag.String("email", "", "user email")
-	passwordPtr := flag.String("password", "", "user password")
-	flag.Parse()
+	defer db.Close()
 
-	email := *emailPtr
-	password := *passwordPtr
+	//	pass the storage layer instance to the scripts instance
+	scripts := scripts.NewScript(storage.NewStorage(db))
 
-	//	run the scripts.CreateVerifiedUser() func
-	user, err := scripts.CreateVerifiedUser(email, password)
-	if err != nil {
-		log.Fatalf("Error creating verified user: %v\n", err)
+	if err := run(scripts); err != nil {
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", args[0], args[1], err)
+		db.Close()
+		os.Exit(1)
 	}
-
-	log.Printf("Created User: %v\n", user)
 }
 
 func connectToPostgresDb(dbConnStr string) (*sqlx.DB, error) {
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	OUTPUT_TABLE = "table"
+	OUTPUT_JSON  = "json"
+)
+
+// newFlagSet a flag set for a subcommand with the shared -output flag
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	output := fs.String("output", OUTPUT_TABLE, "output format, table or json")
+
+	return fs, output
+}
+
+// parseFlags parses the subcommand's args and validates -output
+func parseFlags(fs *flag.FlagSet, output *string, args []string) error {
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	if *output != OUTPUT_TABLE && *output != OUTPUT_JSON {
+		return fmt.Errorf("invalid -output %q, use table or json", *output)
+	}
+
+	return nil
+}
+
+// writeOutput prints v as indented json, or headers and rows as an aligned table
+func writeOutput(output string, v any, headers []string, rows [][]string) error {
+
+	if output == OUTPUT_JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// optionalBoolFlag nil when the flag was left empty
+func optionalBoolFlag(name string, value string) (*bool, error) {
+
+	if value == "" {
+		return nil, nil
+	}
+
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -%s %q, use true or false", name, value)
+	}
+
+	return &boolValue, nil
+}
+
+func stringOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/dhruv15803/go-blog-app/scripts"
+	"os"
+	"strconv"
+)
+
+var topicHeaders = []string{"ID", "TOPIC NAME", "CREATED AT"}
+
+func topicRows(topics []storage.Topic) [][]string {
+
+	var rows [][]string
+	for _, topic := range topics {
+		rows = append(rows, []string{strconv.Itoa(topic.Id), topic.TopicName, topic.CreatedAt})
+	}
+
+	return rows
+}
+
+// runTopicsExport with -file the topics are written there as json (the format topics import reads)
+func runTopicsExport(args []string) (action, error) {
+
+	fs, output := newFlagSet("topics export")
+	file := fs.String("file", "", "write the topics to this json file instead of stdout")
+	if err := parseFlags(fs, output, args); err != nil {
+		return nil, err
+	}
+
+	return func(s *scripts.Script) error {
+		topics, err := s.ExportTopics()
+		if err != nil {
+			return err
+		}
+
+		if *file == "" {
+			return writeOutput(*output, topics, topicHeaders, topicRows(topics))
+		}
+
+		data, err := json.MarshalIndent(topics, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(*file, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+
+		type Result struct {
+			File          string `json:"file"`
+			ExportedCount int    `json:"exported_count"`
+		}
+
+		return writeOutput(*output, Result{File: *file, ExportedCount: len(topics)},
+			[]string{"FILE", "EXPORTED"}, [][]string{{*file, strconv.Itoa(len(topics))}})
+	}, nil
+}
+
+// runTopicsImport reads a json array of objects with a topic_name, e.g. the file of topics export
+func runTopicsImport(args []string) (action, error) {
+
+	fs, output := newFlagSet("topics import")
+	file := fs.String("file", "", "json file with the topics to import")
+	if err := parseFlags(fs, output, args); err != nil {
+		return nil, err
+	}
+
+	if *file == "" {
+		return nil, errors.New("-file is required")
+	}
+
+	return func(s *scripts.Script) error {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+
+		var topics []storage.Topic
+		if err := json.Unmarshal(data, &topics); err != nil {
+			return errors.New("invalid topics file, expected a json array like [{\"topic_name\": \"go\"}]")
+		}
+
+		var topicNames []string
+		for _, topic := range topics {
+			topicNames = append(topicNames, topic.TopicName)
+		}
+
+		createdTopics, skippedTopicNames, err := s.ImportTopics(topicNames)
+		if err != nil {
+			return err
+		}
+
+		type Result struct {
+			Created []storage.Topic `json:"created"`
+			Skipped []string        `json:"skipped"` // already existing topics
+		}
+
+		rows := topicRows(createdTopics)
+		for _, topicName := range skippedTopicNames {
+			rows = append(rows, []string{"-", topicName, "already exists"})
+		}
+
+		return writeOutput(*output, Result{Created: createdTopics, Skipped: skippedTopicNames}, topicHeaders, rows)
+	}, nil
+}
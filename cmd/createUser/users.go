@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/dhruv15803/go-blog-app/scripts"
+	"strconv"
+)
+
+var userHeaders = []string{"ID", "EMAIL", "USERNAME", "ROLE", "VERIFIED", "CREATED AT"}
+
+func userRow(user storage.User) []string {
+	return []string{strconv.Itoa(user.Id), user.Email, stringOrEmpty(user.Username), string(user.Role),
+		strconv.FormatBool(user.IsVerified), user.CreatedAt}
+}
+
+func writeUser(output string, user *storage.User) error {
+	return writeOutput(output, user, userHeaders, [][]string{userRow(*user)})
+}
+
+func runUsersCreate(args []string) (action, error) {
+
+	fs, output := newFlagSet("users create")
+	email := fs.String("email", "", "user email")
+	password := fs.String("password", "", "user password")
+	role := fs.String("role", string(storage.RoleUser), "user, admin, moderator or editor")
+	if err := parseFlags(fs, output, args); err != nil {
+		return nil, err
+	}
+
+	//	before the user is created, an invalid role must not leave a regular user behind
+	if !scripts.IsValidRole(storage.UserRole(*role)) {
+		return nil, errors.New("invalid role, use one of user, admin, moderator, editor")
+	}
+
+	return func(s *scripts.Script) error {
+		user, err := s.CreateVerifiedUser(*email, *password)
+		if err != nil {
+			return err
+		}
+
+		if storage.UserRole(*role) != storage.RoleUser {
+			if user, err = s.SetUserRole(user.Email, storage.UserRole(*role)); err != nil {
+				return err
+			}
+		}
+
+		return writeUser(*output, user)
+	}, nil
+}
+
+func runUsersList(args []string) (action, error) {
+
+	fs, output := newFlagSet("users list")
+	searchText := fs.String("q", "", "part of the email or username")
+	role := fs.String("role", "", "only users with this role")
+	verified := fs.String("verified", "", "only verified (true) or unverified (false) users")
+	suspended := fs.String("suspended", "", "only suspended (true) or not suspended (false) users")
+	page := fs.Int("page", 1, "page")
+	limit := fs.Int("limit", 20, "users per page")
+	if err := parseFlags(fs, output, args); err != nil {
+		return nil, err
+	}
+
+	var userRole *storage.UserRole
+	if *role != "" {
+		r := storage.UserRole(*role)
+		userRole = &r
+	}
+
+	isVerified, err := optionalBoolFlag("verified", *verified)
+	if err != nil {
+		return nil, err
+	}
+
+	isSuspended, err := optionalBoolFlag("suspended", *suspended)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(s *scripts.Script) error {
+		users, totalCount, err := s.ListUsers(*searchText, userRole, isVerified, isSuspended, *page, *limit)
+		if err != nil {
+			return err
+		}
+
+		type Result struct {
+			Users      []storage.UserWithAccountState `json:"users"`
+			TotalCount int                            `json:"total_count"`
+		}
+
+		headers := []string{"ID", "EMAIL", "USERNAME", "ROLE", "VERIFIED", "2FA", "SUSPENDED", "CREATED AT"}
+
+		var rows [][]string
+		for _, user := range users {
+			suspended := "false"
+			if user.Suspension != nil {
+				suspended = "until " + stringOrEmpty(user.Suspension.SuspendedUntil)
+				if user.Suspension.SuspendedUntil == nil {
+					suspended = "banned"
+				}
+			}
+
+			rows = append(rows, []string{strconv.Itoa(user.Id), user.Email, stringOrEmpty(user.Username), string(user.Role),
+				strconv.FormatBool(user.IsVerified), strconv.FormatBool(user.IsTwoFactorEnabled), suspended, user.CreatedAt})
+		}
+		rows = append(rows, []string{"", "total: " + strconv.Itoa(totalCount)})
+
+		return writeOutput(*output, Result{Users: users, TotalCount: totalCount}, headers, rows)
+	}, nil
+}
+
+func runUsersPromote(args []string) (action, error) {
+
+	fs, output := newFlagSet("users promote")
+	email := fs.String("email", "", "email of a verified user")
+	role := fs.String("role", string(storage.RoleAdmin), "admin, moderator or editor")
+	if err := parseFlags(fs, output, args); err != nil {
+		return nil, err
+	}
+
+	if storage.UserRole(*role) == storage.RoleUser {
+		return nil, errors.New("use demote to make a user a regular user again")
+	}
+
+	return func(s *scripts.Script) error {
+		user, err := s.SetUserRole(*email, storage.UserRole(*role))
+		if err != nil {
+			return err
+		}
+
+		return writeUser(*output, user)
+	}, nil
+}
+
+func runUsersDemote(args []string) (action, error) {
+
+	fs, output := newFlagSet("users demote")
+	email := fs.String("email", "", "email of a verified user")
+	if err := parseFlags(fs, output, args); err != nil {
+		return nil, err
+	}
+
+	return func(s *scripts.Script) error {
+		user, err := s.SetUserRole(*email, storage.RoleUser)
+		if err != nil {
+			return err
+		}
+
+		return writeUser(*output, user)
+	}, nil
+}
+
+func runUsersDisable(args []string) (action, error) {
+
+	fs, output := newFlagSet("users disable")
+	email := fs.String("email", "", "email of a verified user")
+	reason := fs.String("reason", "", "shown to the user when they try to log in")
+	if err := parseFlags(fs, output, args); err != nil {
+		return nil, err
+	}
+
+	return func(s *scripts.Script) error {
+		user, err := s.DisableUser(*email, *reason)
+		if err != nil {
+			return err
+		}
+
+		return writeUser(*output, user)
+	}, nil
+}
+
+func runUsersEnable(args []string) (action, error) {
+
+	fs, output := newFlagSet("users enable")
+	email := fs.String("email", "", "email of a verified user")
+	if err := parseFlags(fs, output, args); err != nil {
+		return nil, err
+	}
+
+	return func(s *scripts.Script) error {
+		user, err := s.EnableUser(*email)
+		if err != nil {
+			return err
+		}
+
+		return writeUser(*output, user)
+	}, nil
+}
+
+func runUsersResetPassword(args []string) (action, error) {
+
+	fs, output := newFlagSet("users reset-password")
+	email := fs.String("email", "", "email of a verified user")
+	password := fs.String("password", "", "new password")
+	if err := parseFlags(fs, output, args); err != nil {
+		return nil, err
+	}
+
+	return func(s *scripts.Script) error {
+		user, err := s.ResetUserPassword(*email, *password)
+		if err != nil {
+			return err
+		}
+
+		return writeUser(*output, user)
+	}, nil
+}
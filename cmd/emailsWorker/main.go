@@ -66,16 +66,35 @@ func loadConfig() (*config, error) {
 	return &config{redisConfig: redisCfg, mailerConfig: mailerCfg}, nil
 }
 
-type VerificationMailData struct {
-	Subject       string `json:"subject"`
-	Email         string `json:"email"`
-	ActivationUrl string `json:"activation_url"`
+// EmailData email_type decides which template is rendered, jobs without
+// an email_type are verification emails (pushed before email types existed)
+type EmailData struct {
+	EmailType        string `json:"email_type"`
+	Subject          string `json:"subject"`
+	Email            string `json:"email"`
+	ActivationUrl    string `json:"activation_url"`
+	ResetPasswordUrl string `json:"reset_password_url"`
+	MagicLinkUrl     string `json:"magic_link_url"`
+	EmailChangeUrl   string `json:"email_change_url"`
+	NewEmail         string `json:"new_email"`
+	IpAddress        string `json:"ip_address"`
+	FailedAttempts   int    `json:"failed_attempts"`
 }
 
 const (
 	MAX_RETRIES_PER_EMAIL = 3
 )
 
+var emailTemplates = map[string]string{
+	"":                    "./templates/verification.html",
+	"verification":        "./templates/verification.html",
+	"password_reset":      "./templates/passwordReset.html",
+	"magic_link":          "./templates/magicLink.html",
+	"email_change":        "./templates/emailChange.html",
+	"email_change_notice": "./templates/emailChangeNotice.html",
+	"suspicious_login":    "./templates/suspiciousLogin.html",
+}
+
 func main() {
 
 	cfg, err := loadConfig()
@@ -92,7 +111,7 @@ func main() {
 
 	log.Println("Email Worker started!")
 	for {
-		var emailDataFromRedis VerificationMailData
+		var emailDataFromRedis EmailData
 
 		emailDataArr, err := redisClient.BRPop(context.Background(), 0, "emails").Result()
 		if err != nil {
@@ -107,11 +126,18 @@ func main() {
 			continue
 		}
 
+		templatePath, ok := emailTemplates[emailDataFromRedis.EmailType]
+		if !ok {
+			log.Printf("unknown email type %s", emailDataFromRedis.EmailType)
+			redisClient.LPush(context.Background(), "emails-dead", emailDataStr)
+			continue
+		}
+
 		isEmailSent := false
 		for i := 0; i < MAX_RETRIES_PER_EMAIL; i++ {
 
-			if err := mailer.SendMailFromTemplate(emailDataFromRedis.Email, emailDataFromRedis.Subject, "./templates/verification.html", emailDataFromRedis); err != nil {
-				log.Printf("failed to send verification email to %s , attempt=%d", emailDataFromRedis.Email, i+1)
+			if err := mailer.SendMailFromTemplate(emailDataFromRedis.Email, emailDataFromRedis.Subject, templatePath, emailDataFromRedis); err != nil {
+				log.Printf("failed to send %s email to %s , attempt=%d", emailDataFromRedis.EmailType, emailDataFromRedis.Email, i+1)
 				continue
 			}
 			isEmailSent = true
@@ -123,6 +149,6 @@ func main() {
 			continue
 		}
 
-		log.Printf("Verification Email Successfully sent, Email:%s", emailDataFromRedis.Email)
+		log.Printf("Email Successfully sent, Type:%s Email:%s", emailDataFromRedis.EmailType, emailDataFromRedis.Email)
 	}
 }
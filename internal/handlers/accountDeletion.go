@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DeleteMeRequest keep_content defaults to true: published blogs and comments stay up attributed
+// to a "deleted user" instead of being deleted along with the replies under them
+type DeleteMeRequest struct {
+	Password    string `json:"password"`
+	KeepContent *bool  `json:"keep_content"`
+}
+
+// the accountCleanup worker purges the user once the grace period ends, logging in again before that cancels the deletion
+const ACCOUNT_DELETION_GRACE_PERIOD = time.Hour * 24 * 30
+
+// DeleteMeHandler schedules the deletion of the authenticated user's account and logs them out everywhere
+func (h *Handler) DeleteMeHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var deleteMePayload DeleteMeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&deleteMePayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userPlainTextPassword := strings.TrimSpace(deleteMePayload.Password)
+
+	if userPlainTextPassword == "" {
+		writeJSONError(w, "password is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(userPlainTextPassword)); err != nil {
+		writeJSONError(w, "invalid password", http.StatusBadRequest)
+		return
+	}
+
+	keepContent := true
+	if deleteMePayload.KeepContent != nil {
+		keepContent = *deleteMePayload.KeepContent
+	}
+
+	deletionScheduledAt := time.Now().Add(ACCOUNT_DELETION_GRACE_PERIOD)
+
+	if err := h.storage.ScheduleUserDeletion(user.Id, deletionScheduledAt, keepContent); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to schedule user deletion: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	clearAuthCookies(w)
+
+	type Response struct {
+		Success             bool      `json:"success"`
+		Message             string    `json:"message"`
+		DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+		KeepContent         bool      `json:"keep_content"`
+	}
+
+	if err := writeJSON(w, Response{
+		Success:             true,
+		Message:             "account scheduled for deletion, log in again before deletion_scheduled_at to cancel",
+		DeletionScheduledAt: deletionScheduledAt,
+		KeepContent:         keepContent,
+	}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
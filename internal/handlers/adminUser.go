@@ -0,0 +1,477 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// endpoints under /admin/users, user:manage for listing, roles, verification and password resets,
+// user:ban for suspensions and bans
+
+type UpdateUserRoleRequest struct {
+	Role storage.UserRole `json:"role"`
+}
+
+// SuspendUserRequest until is an RFC 3339 timestamp, bans (POST /ban) have no until
+type SuspendUserRequest struct {
+	Reason string `json:"reason"`
+	Until  string `json:"until"`
+}
+
+const MAX_SUSPENSION_REASON_LENGTH = 500
+
+func (h *Handler) GetAdminUsersHandler(w http.ResponseWriter, r *http.Request) {
+
+	searchText := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	var role *storage.UserRole
+
+	switch queryRole := storage.UserRole(r.URL.Query().Get("role")); queryRole {
+	case "":
+	case storage.RoleUser, storage.RoleAdmin, storage.RoleModerator, storage.RoleEditor:
+		role = &queryRole
+	default:
+		writeJSONError(w, "invalid query param role", http.StatusBadRequest)
+		return
+	}
+
+	isVerified, err := optionalBoolQueryParam(r, "is_verified")
+	if err != nil {
+		writeJSONError(w, "invalid query param is_verified", http.StatusBadRequest)
+		return
+	}
+
+	isSuspended, err := optionalBoolQueryParam(r, "is_suspended")
+	if err != nil {
+		writeJSONError(w, "invalid query param is_suspended", http.StatusBadRequest)
+		return
+	}
+
+	var page int
+	var limit int
+
+	if r.URL.Query().Get("page") == "" {
+		page = 1
+	} else {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+			return
+		}
+	}
+	if r.URL.Query().Get("limit") == "" {
+		limit = 20
+	} else {
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil {
+			writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	skip := page*limit - limit
+
+	users, err := h.storage.GetUsers(searchText, role, isVerified, isSuspended, skip, limit)
+	if err != nil {
+		log.Printf("failed to get users: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalUsersCount, err := h.storage.GetUsersCount(searchText, role, isVerified, isSuspended)
+	if err != nil {
+		log.Printf("failed to get users count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalUsersCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool                           `json:"success"`
+		Users     []storage.UserWithAccountState `json:"users"`
+		NoOfPages int                            `json:"no_of_pages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Users: users, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) GetAdminUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	user, ok := h.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	type Response struct {
+		Success bool                         `json:"success"`
+		User    storage.UserWithAccountState `json:"user"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, User: *user}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// UpdateUserRoleHandler admins cannot change their own role, so the last admin cannot lock everyone out
+func (h *Handler) UpdateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+
+	authUserId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var updateUserRolePayload UpdateUserRoleRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&updateUserRolePayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	switch updateUserRolePayload.Role {
+	case storage.RoleUser, storage.RoleAdmin, storage.RoleModerator, storage.RoleEditor:
+	default:
+		writeJSONError(w, "invalid role, use one of user, admin, moderator, editor", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	if user.Id == authUserId {
+		writeJSONError(w, "cannot change your own role", http.StatusBadRequest)
+		return
+	}
+
+	updatedUser, err := h.storage.UpdateUserRole(user.Id, updateUserRolePayload.Role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to update user role: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success bool         `json:"success"`
+		Message string       `json:"message"`
+		User    storage.User `json:"user"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "user role updated", User: *updatedUser}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// SuspendUserHandler suspends the user until the given time, suspended users cannot log in or use
+// authenticated endpoints and their blogs are left out of feeds
+func (h *Handler) SuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	var suspendUserPayload SuspendUserRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&suspendUserPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	suspendedUntil, err := time.Parse(time.RFC3339, strings.TrimSpace(suspendUserPayload.Until))
+	if err != nil {
+		writeJSONError(w, "invalid until, expected an RFC 3339 timestamp like 2025-01-02T15:04:05Z", http.StatusBadRequest)
+		return
+	}
+
+	if !suspendedUntil.After(time.Now()) {
+		writeJSONError(w, "until should be in the future", http.StatusBadRequest)
+		return
+	}
+
+	//	suspended_until is a TIMESTAMP holding the server's local time like suspended_at (time.Now()),
+	//	the caller's offset would otherwise be dropped
+	suspendedUntil = suspendedUntil.Local()
+
+	h.suspendUser(w, r, &suspendedUntil, suspendUserPayload.Reason)
+}
+
+// BanUserHandler a suspension without an end, lifted with UnsuspendUserHandler
+func (h *Handler) BanUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	var banUserPayload SuspendUserRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&banUserPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	h.suspendUser(w, r, nil, banUserPayload.Reason)
+}
+
+func (h *Handler) suspendUser(w http.ResponseWriter, r *http.Request, suspendedUntil *time.Time, reason string) {
+
+	authUserId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	suspensionReason := strings.TrimSpace(reason)
+
+	if utf8.RuneCountInString(suspensionReason) > MAX_SUSPENSION_REASON_LENGTH {
+		writeJSONError(w, fmt.Sprintf("reason can have max %v characters", MAX_SUSPENSION_REASON_LENGTH), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.canSuspendUser(w, r, authUserId, user) {
+		return
+	}
+
+	if err := h.storage.SuspendUser(user.Id, &authUserId, suspendedUntil, suspensionReason); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to suspend user: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	message := "user banned"
+	if suspendedUntil != nil {
+		message = "user suspended"
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: message}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// UnsuspendUserHandler lifts a suspension or a ban
+func (h *Handler) UnsuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	authUserId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, ok := h.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.canSuspendUser(w, r, authUserId, user) {
+		return
+	}
+
+	if err := h.storage.UnsuspendUser(user.Id); err != nil {
+		log.Printf("failed to unsuspend user: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "user unsuspended"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// VerifyUserHandler verifies a user that never used their activation link
+func (h *Handler) VerifyUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	user, ok := h.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	if user.IsVerified {
+		writeJSONError(w, "user is already verified", http.StatusBadRequest)
+		return
+	}
+
+	verifiedUser, err := h.storage.ForceVerifyUser(user.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else if errors.Is(err, storage.ErrEmailTaken) {
+			writeJSONError(w, "another verified user already has this email", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to force verify user: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success bool         `json:"success"`
+		Message string       `json:"message"`
+		User    storage.User `json:"user"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "user verified", User: *verifiedUser}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// TriggerPasswordResetHandler emails the user a password reset link, the same one forgot password sends
+func (h *Handler) TriggerPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+
+	user, ok := h.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	if !user.IsVerified {
+		writeJSONError(w, "user is not verified", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.sendPasswordResetEmail(&user.User); err != nil {
+		log.Printf("failed to send password reset email: %v\n", err)
+		writeJSONError(w, "server failed to send password reset mail", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "a password reset mail will be sent to the user shortly"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// adminTargetUser the user of the userId url param, writes the error response when ok is false
+func (h *Handler) adminTargetUser(w http.ResponseWriter, r *http.Request) (*storage.UserWithAccountState, bool) {
+
+	userId, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param userId", http.StatusBadRequest)
+		return nil, false
+	}
+
+	user, err := h.storage.GetUserWithAccountState(int(userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return nil, false
+		} else {
+			log.Printf("failed to get user with account state: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return nil, false
+		}
+	}
+
+	return user, true
+}
+
+// canSuspendUser nobody suspends themselves, and staff users can only be suspended by users with user:manage
+// (a moderator cannot ban an admin), writes the error response when false
+func (h *Handler) canSuspendUser(w http.ResponseWriter, r *http.Request, authUserId int, user *storage.UserWithAccountState) bool {
+
+	if user.Id == authUserId {
+		writeJSONError(w, "cannot suspend yourself", http.StatusBadRequest)
+		return false
+	}
+
+	if !isStaffRole(user.Role) {
+		return true
+	}
+
+	authUser, err := h.storage.GetUserById(authUserId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return false
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return false
+		}
+	}
+
+	if !RoleHasPermission(authUser.Role, PermissionUserManage) {
+		writeJSONError(w, "missing permission "+string(PermissionUserManage)+" to suspend staff users", http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// getActiveUserSuspension nil when the user is not suspended
+func (h *Handler) getActiveUserSuspension(userId int) (*storage.UserSuspension, error) {
+
+	userSuspension, err := h.storage.GetActiveUserSuspension(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return userSuspension, nil
+}
+
+func suspensionErrorMessage(userSuspension *storage.UserSuspension) string {
+
+	message := "account banned"
+	if userSuspension.SuspendedUntil != nil {
+		message = "account suspended until " + *userSuspension.SuspendedUntil
+	}
+
+	if userSuspension.SuspensionReason != nil {
+		message += ": " + *userSuspension.SuspensionReason
+	}
+
+	return message
+}
+
+// optionalBoolQueryParam nil when the query param is not set
+func optionalBoolQueryParam(r *http.Request, key string) (*bool, error) {
+
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &boolValue, nil
+}
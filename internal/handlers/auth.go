@@ -26,14 +26,28 @@ type RegisterUserRequest struct {
 	Password string `json:"password"`
 }
 
+type ResendActivationRequest struct {
+	Email string `json:"email"`
+}
+
+const (
+	INVITATION_EXPIRATION = time.Minute * 15
+	// activation email resends per email address within the rate limit window
+	MAX_RESEND_ACTIVATION_REQUESTS          = 3
+	RESEND_ACTIVATION_RATE_LIMIT_WINDOW     = time.Minute * 15
+	RESEND_ACTIVATION_RATE_LIMIT_KEY_PREFIX = "resend_activation_requests:"
+)
+
 type LoginUserRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
 var (
-	JWT_SECRET = []byte(os.Getenv("JWT_SECRET"))
-	AuthUserId = "AuthUserId"
+	JWT_SECRET      = []byte(os.Getenv("JWT_SECRET"))
+	AuthUserId      = "AuthUserId"
+	AuthSessionId   = "AuthSessionId"
+	AuthTokenScopes = "AuthTokenScopes"
 )
 
 func (h *Handler) RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -89,7 +103,7 @@ func (h *Handler) RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	inviteExpiration := time.Now().Add(time.Minute * 15)
+	inviteExpiration := time.Now().Add(INVITATION_EXPIRATION)
 
 	//	create user and invite
 	user, err := h.storage.CreateUserAndInvite(userEmail, string(hashedPassword), hashedTokenStr, inviteExpiration)
@@ -99,38 +113,14 @@ func (h *Handler) RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	type VerificationMailData struct {
-		Subject       string `json:"subject"`
-		Email         string `json:"email"`
-		ActivationUrl string `json:"activation_url"`
-	}
-	verificationMailData := VerificationMailData{
+	verificationMailData := EmailData{
+		EmailType:     EmailTypeVerification,
 		Subject:       "Verify your account",
 		Email:         user.Email,
 		ActivationUrl: fmt.Sprintf("%s/activate-account/%s", h.clientUrl, plainTextToken),
 	}
 
-	emailDataJsonBytes, err := json.Marshal(verificationMailData)
-	if err != nil {
-		log.Printf("failed to marshal verification mail data: %v\n", err)
-		writeJSONError(w, "internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// push this job(email job) onto the emails job queue (to be processed by background worker)
-	maxRedisQueueRetries := 3
-	isEmailDataPushed := false
-
-	for i := 0; i < maxRedisQueueRetries; i++ {
-		if err := h.redisClient.LPush(context.Background(), "emails", string(emailDataJsonBytes)).Err(); err != nil {
-			log.Printf("failed to push email data to redis queue, attempt:%d\n", i+1)
-			continue
-		}
-		isEmailDataPushed = true
-		break
-	}
-
-	if !isEmailDataPushed {
+	if err := h.pushEmailJob(verificationMailData); err != nil {
 		log.Printf("failed to push email job to redis queue: %v\n", err)
 		writeJSONError(w, "server failed to send verification mail, please contact support", http.StatusInternalServerError)
 		return
@@ -161,45 +151,118 @@ func (h *Handler) ActivateUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//	create jwt token with user.Id payload and expiration and set using cookie
-	claims := jwt.MapClaims{
-		"sub": user.Id,
-		"exp": time.Now().Add(time.Hour * 24 * 2).Unix(),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenStr, err := token.SignedString(JWT_SECRET)
+	//	start a server side session, the access and refresh tokens are set as cookies (or returned in the body)
+	sessionTokens, err := h.startUserSession(w, r, user.Id)
 	if err != nil {
+		log.Printf("failed to start user session: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	var sameSiteConfig http.SameSite
+	type Response struct {
+		Success bool         `json:"success"`
+		Message string       `json:"message"`
+		User    storage.User `json:"user"`
+		*SessionTokens
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "activated user successfully", User: *user, SessionTokens: sessionTokens}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// ResendActivationHandler emails a new activation link for the latest pending registration of the email,
+// the previous links stop working
+func (h *Handler) ResendActivationHandler(w http.ResponseWriter, r *http.Request) {
+
+	var resendActivationPayload ResendActivationRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&resendActivationPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userEmail := strings.ToLower(strings.TrimSpace(resendActivationPayload.Email))
 
-	if os.Getenv("GO_ENV") == "development" {
-		sameSiteConfig = http.SameSiteLaxMode
-	} else {
-		sameSiteConfig = http.SameSiteNoneMode
+	if userEmail == "" {
+		writeJSONError(w, "email is required", http.StatusBadRequest)
+		return
 	}
 
-	cookie := http.Cookie{
-		Name:     "auth_token",
-		Value:    tokenStr,
-		HttpOnly: true,
-		Path:     "/",
-		Secure:   os.Getenv("GO_ENV") == "production",
-		MaxAge:   60 * 60 * 24 * 2,
-		SameSite: sameSiteConfig,
+	if !isValidEmail(userEmail) {
+		writeJSONError(w, "invalid email", http.StatusBadRequest)
+		return
 	}
 
-	http.SetCookie(w, &cookie)
+	isAllowed, err := h.allowRateLimitedRequest(RESEND_ACTIVATION_RATE_LIMIT_KEY_PREFIX+userEmail, MAX_RESEND_ACTIVATION_REQUESTS, RESEND_ACTIVATION_RATE_LIMIT_WINDOW)
+	if err != nil {
+		log.Printf("failed to check resend activation rate limit: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isAllowed {
+		writeJSONError(w, "too many activation email requests, please try again later", http.StatusTooManyRequests)
+		return
+	}
 
 	type Response struct {
-		Success bool         `json:"success"`
-		Message string       `json:"message"`
-		User    storage.User `json:"user"`
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	// the response is the same whether a pending registration exists for the email or not (no user enumeration)
+	response := Response{Success: true, Message: "if a pending registration exists for this email, a verification mail will be sent shortly"}
+
+	existingVerifiedUser, err := h.storage.GetVerifiedUserByEmail(userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to get verified user by email: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetLatestUnverifiedUserByEmail(userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to get unverified user by email: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
 	}
 
-	if err = writeJSON(w, Response{Success: true, Message: "activated user successfully", User: *user}, http.StatusOK); err != nil {
+	//	already activated, or never registered (or cleaned up)
+	if existingVerifiedUser != nil || user == nil {
+		if err := writeJSON(w, response, http.StatusOK); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	plainTextToken, hashedTokenStr, err := generateToken(32)
+	if err != nil {
+		log.Printf("failed to generate token: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.storage.RenewUserInvitation(user.Id, hashedTokenStr, time.Now().Add(INVITATION_EXPIRATION)); err != nil {
+		log.Printf("failed to renew user invitation: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	verificationMailData := EmailData{
+		EmailType:     EmailTypeVerification,
+		Subject:       "Verify your account",
+		Email:         user.Email,
+		ActivationUrl: fmt.Sprintf("%s/activate-account/%s", h.clientUrl, plainTextToken),
+	}
+
+	if err := h.pushEmailJob(verificationMailData); err != nil {
+		log.Printf("failed to push email job to redis queue: %v\n", err)
+		writeJSONError(w, "server failed to send verification mail, please contact support", http.StatusInternalServerError)
+		return
+	}
+
+	if err := writeJSON(w, response, http.StatusOK); err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 	}
 }
@@ -221,60 +284,135 @@ func (h *Handler) LoginUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.storage.GetVerifiedUserByEmail(userEmail)
+	ipAddress := h.clientIpAddress(r)
+
+	//	checked before the password, a locked out account stays locked out for the right password too
+	retryAfter, err := h.loginRetryAfter(userEmail, ipAddress)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			writeJSONError(w, "invalid email or password", http.StatusBadRequest)
-			return
-		} else {
+		log.Printf("failed to check login throttle: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if retryAfter > 0 {
+		writeRetryAfterError(w, "too many failed login attempts, please try again later", retryAfter)
+		return
+	}
+
+	user, err := h.storage.GetVerifiedUserByEmail(userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	//	an unknown email is throttled, timed and answered exactly like a wrong password (no user enumeration)
+	passwordHash := dummyPasswordHash()
+	if user != nil {
+		passwordHash = []byte(user.Password)
+	}
+
+	if err := bcrypt.CompareHashAndPassword(passwordHash, []byte(userPlainTextPassword)); err != nil || user == nil {
+
+		if err := h.recordLoginFailure(userEmail, ipAddress, user); err != nil {
+			log.Printf("failed to record login failure: %v\n", err)
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
-	}
 
-	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(userPlainTextPassword)); err != nil {
 		writeJSONError(w, "invalid email or password", http.StatusBadRequest)
+		return
 	}
 
-	claims := jwt.MapClaims{
-		"sub": user.Id,
-		"exp": time.Now().Add(time.Hour * 24 * 2).Unix(),
+	if err := accountLoginThrottle.clearFailures(h.redisClient, userEmail); err != nil {
+		log.Printf("failed to clear login failures: %v\n", err)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenStr, err := token.SignedString(JWT_SECRET)
+	h.completeLogin(w, r, user)
+}
+
+// completeLogin once the first factor (password, magic link) has been verified: rejects suspended users,
+// responds with a 2fa challenge when the user has 2fa enabled, else starts the session and responds with the user
+func (h *Handler) completeLogin(w http.ResponseWriter, r *http.Request, user *storage.User) {
+
+	userSuspension, err := h.getActiveUserSuspension(user.Id)
 	if err != nil {
+		log.Printf("failed to get user suspension: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	var sameSiteConfig http.SameSite
+	//	only reached with a valid first factor, so this does not reveal anything to a password guesser
+	if userSuspension != nil {
+		writeJSONError(w, suspensionErrorMessage(userSuspension), http.StatusForbidden)
+		return
+	}
 
-	if os.Getenv("GO_ENV") == "development" {
-		sameSiteConfig = http.SameSiteLaxMode
-	} else {
-		sameSiteConfig = http.SameSiteNoneMode
+	isTwoFactorEnabled, err := h.storage.IsUserTwoFactorEnabled(user.Id)
+	if err != nil {
+		log.Printf("failed to check user two factor: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
 	}
 
-	cookie := http.Cookie{
-		Name:     "auth_token",
-		Value:    tokenStr,
-		HttpOnly: true,
-		Path:     "/",
-		Secure:   os.Getenv("GO_ENV") == "production",
-		MaxAge:   60 * 60 * 24 * 2,
-		SameSite: sameSiteConfig,
+	//	with 2fa enabled the password only earns a short lived challenge token, the session is
+	//	started by LoginTwoFactorHandler once a valid code is sent with it
+	if isTwoFactorEnabled {
+
+		challengeToken, err := h.startTwoFactorChallenge(user.Id)
+		if err != nil {
+			log.Printf("failed to start two factor challenge: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		type Response struct {
+			Success           bool   `json:"success"`
+			Message           string `json:"message"`
+			TwoFactorRequired bool   `json:"two_factor_required"`
+			ChallengeToken    string `json:"challenge_token"`
+		}
+
+		if err := writeJSON(w, Response{
+			Success:           true,
+			Message:           "two factor code required",
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+		}, http.StatusOK); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
 	}
 
-	http.SetCookie(w, &cookie)
+	isTwoFactorSetupRequired, err := h.isTwoFactorSetupRequired(user)
+	if err != nil {
+		log.Printf("failed to check two factor setup: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
 
-	type Response struct {
-		Success bool         `json:"success"`
-		Message string       `json:"message"`
-		User    storage.User `json:"user"`
+	//	start a server side session, the access and refresh tokens are set as cookies (or returned in the body)
+	sessionTokens, err := h.startUserSession(w, r, user.Id)
+	if err != nil {
+		log.Printf("failed to start user session: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
 	}
 
-	if err := writeJSON(w, Response{Success: true, Message: "logged in user successfully", User: *user}, http.StatusOK); err != nil {
+	type Response struct {
+		Success                bool         `json:"success"`
+		Message                string       `json:"message"`
+		User                   storage.User `json:"user"`
+		TwoFactorSetupRequired bool         `json:"two_factor_setup_required"` // staff routes stay blocked until 2fa is enabled
+		*SessionTokens
+	}
+
+	if err := writeJSON(w, Response{
+		Success:                true,
+		Message:                "logged in user successfully",
+		User:                   *user,
+		TwoFactorSetupRequired: isTwoFactorSetupRequired,
+		SessionTokens:          sessionTokens,
+	}, http.StatusOK); err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 	}
 }
@@ -289,7 +427,7 @@ func (h *Handler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.storage.GetUserById(userId)
+	user, err := h.storage.GetUserWithFollowCounts(userId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			writeJSONError(w, "user does not exist", http.StatusBadRequest)
@@ -301,11 +439,12 @@ func (h *Handler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type Response struct {
-		Success bool         `json:"success"`
-		User    storage.User `json:"user"`
+		Success     bool                         `json:"success"`
+		User        storage.UserWithFollowCounts `json:"user"`
+		Permissions []Permission                 `json:"permissions"` // granted by the user's role
 	}
 
-	if err := writeJSON(w, Response{Success: true, User: *user}, http.StatusOK); err != nil {
+	if err := writeJSON(w, Response{Success: true, User: *user, Permissions: RolePermissions(user.Role)}, http.StatusOK); err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 	}
 }
@@ -314,148 +453,162 @@ func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		cookie, err := r.Cookie("auth_token")
+		userId, sessionId, tokenScopes, err := h.authenticateRequest(r)
 		if err != nil {
-			writeJSONError(w, "unauthorized", http.StatusUnauthorized)
-			return
-		}
-
-		tokenStr := cookie.Value
-
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			if errors.Is(err, errNotAuthenticated) {
+				writeJSONError(w, "unauthorized", http.StatusUnauthorized)
+				return
+			} else {
+				log.Printf("failed to authenticate request: %v\n", err)
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
 			}
+		}
 
-			return JWT_SECRET, nil
-		})
+		userSuspension, err := h.getActiveUserSuspension(userId)
 		if err != nil {
-			log.Printf("failed to parse token string with secret: %v\n", err)
+			log.Printf("failed to get user suspension: %v\n", err)
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-
-			exp, ok := claims["exp"].(float64)
-			if !ok {
-				writeJSONError(w, "internal server error", http.StatusInternalServerError)
-				return
-			}
-
-			if time.Now().Unix() > int64(exp) {
-				log.Printf("token is expired")
-				writeJSONError(w, "unauthorized", http.StatusUnauthorized)
-				return
-			}
-
-			userIdFloat, ok := claims["sub"].(float64)
-			if !ok {
-				log.Println("failed to parse user id from token")
-				writeJSONError(w, "internal server error", http.StatusInternalServerError)
-				return
-			}
-			userId := int(userIdFloat)
+		if userSuspension != nil {
+			writeJSONError(w, suspensionErrorMessage(userSuspension), http.StatusForbidden)
+			return
+		}
 
-			ctx := context.WithValue(r.Context(), AuthUserId, userId)
-			r = r.WithContext(ctx)
-			next.ServeHTTP(w, r)
-		} else {
-			log.Println("invalid token")
-			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		if tokenScopes != nil && !isTokenScopeAllowed(r, tokenScopes) {
+			writeJSONError(w, "access token does not have the required scope", http.StatusForbidden)
 			return
 		}
+
+		ctx := context.WithValue(r.Context(), AuthUserId, userId)
+		ctx = context.WithValue(ctx, AuthSessionId, sessionId)
+		ctx = context.WithValue(ctx, AuthTokenScopes, tokenScopes)
+		r = r.WithContext(ctx)
+		next.ServeHTTP(w, r)
 	})
 }
 
-func (h *Handler) AdminAuthMiddleware(next http.Handler) http.Handler {
+func (h *Handler) OptionalAuthMiddleware(next http.Handler) http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		//	admin role will be checked after the auth middleware decodes and validates the jwt
-
-		userId, ok := r.Context().Value(AuthUserId).(int)
-		if !ok {
-			writeJSONError(w, "internal server error", http.StatusInternalServerError)
-			return
-		}
-
-		user, err := h.storage.GetUserById(userId)
+		userId, sessionId, tokenScopes, err := h.authenticateRequest(r)
 		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-
-				writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			if errors.Is(err, errNotAuthenticated) {
+				//	no token, or an expired / revoked one (not authenticated)
+				next.ServeHTTP(w, r)
 				return
-
 			} else {
-
+				log.Printf("failed to authenticate request: %v\n", err)
 				writeJSONError(w, "internal server error", http.StatusInternalServerError)
 				return
 			}
 		}
 
-		if user.Role != storage.RoleAdmin {
-			writeJSONError(w, "user does not have admin role", http.StatusUnauthorized)
+		userSuspension, err := h.getActiveUserSuspension(userId)
+		if err != nil {
+			log.Printf("failed to get user suspension: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
 
+		//	suspended users browse public endpoints as anonymous users
+		if userSuspension != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if tokenScopes != nil && !isTokenScopeAllowed(r, tokenScopes) {
+			//	a personal access token without the required scope is treated as anonymous
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), AuthUserId, userId)
+		ctx = context.WithValue(ctx, AuthSessionId, sessionId)
+		ctx = context.WithValue(ctx, AuthTokenScopes, tokenScopes)
+		r = r.WithContext(ctx)
 		next.ServeHTTP(w, r)
 	})
 }
 
-func (h *Handler) OptionalAuthMiddleware(next http.Handler) http.Handler {
+// errNotAuthenticated the request has no access token, or the token is invalid, expired or its session was revoked
+var errNotAuthenticated = errors.New("not authenticated")
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+// authenticateRequest shared by the auth middlewares, the access token is read from the
+// "Authorization: Bearer <jwt>" header or else from the auth_token cookie, both are validated the same way.
+// bearer personal access tokens are also accepted, their scopes are returned (nil scopes for session jwts)
+func (h *Handler) authenticateRequest(r *http.Request) (int, int, []string, error) {
 
-		cookie, err := r.Cookie("auth_token")
+	tokenStr, ok := accessTokenFromRequest(r)
+	if !ok {
+		return -1, -1, nil, errNotAuthenticated
+	}
+
+	if strings.HasPrefix(tokenStr, PERSONAL_ACCESS_TOKEN_PREFIX) {
+		userId, tokenScopes, err := h.authenticatePersonalAccessToken(tokenStr)
 		if err != nil {
-			next.ServeHTTP(w, r)
-			return
+			return -1, -1, nil, err
 		}
+		return userId, -1, tokenScopes, nil
+	}
 
-		tokenStr := cookie.Value
-
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-			}
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 
-			return JWT_SECRET, nil
-		})
-		if err != nil {
-			log.Printf("failed to parse token string with secret: %v\n", err)
-			writeJSONError(w, "internal server error", http.StatusInternalServerError)
-			return
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		return JWT_SECRET, nil
+	}, jwt.WithExpirationRequired())
+	if err != nil {
+		//	expired access tokens end up here, the client is expected to call /auth/refresh
+		return -1, -1, nil, errNotAuthenticated
+	}
 
-			exp, ok := claims["exp"].(float64)
-			if !ok {
-				log.Printf("failed to parse expiration in float64")
-				writeJSONError(w, "internal server error", http.StatusInternalServerError)
-				return
-			}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return -1, -1, nil, errNotAuthenticated
+	}
 
-			if time.Now().Unix() > int64(exp) {
-				//	token expired (not authenticated)
-				next.ServeHTTP(w, r)
-				return
-			}
+	userIdFloat, ok := claims["sub"].(float64)
+	if !ok {
+		return -1, -1, nil, errNotAuthenticated
+	}
+	userId := int(userIdFloat)
 
-			userIdFloat := claims["sub"].(float64)
-			userId := int(userIdFloat)
+	sessionId, isSessionValid, err := h.isUserSessionValid(userId, claims)
+	if err != nil {
+		return -1, -1, nil, err
+	}
+	if !isSessionValid {
+		return -1, -1, nil, errNotAuthenticated
+	}
 
-			ctx := context.WithValue(r.Context(), AuthUserId, userId)
-			r = r.WithContext(ctx)
-			next.ServeHTTP(w, r)
-		} else {
-			log.Println("invalid token")
-			writeJSONError(w, "internal server error", http.StatusInternalServerError)
-			return
+	return userId, sessionId, nil, nil
+}
+
+// accessTokenFromRequest the Authorization header takes precedence over the cookie when both are sent
+func accessTokenFromRequest(r *http.Request) (string, bool) {
+
+	if authorizationHeader := r.Header.Get("Authorization"); authorizationHeader != "" {
+
+		scheme, tokenStr, found := strings.Cut(authorizationHeader, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(tokenStr) == "" {
+			return "", false
 		}
-	})
+
+		return strings.TrimSpace(tokenStr), true
+	}
+
+	cookie, err := r.Cookie(AUTH_TOKEN_COOKIE)
+	if err != nil {
+		return "", false
+	}
+
+	return cookie.Value, true
 }
 
 func isPasswordStrong(password string) bool {
@@ -19,6 +19,13 @@ const (
 	MOST_FOLLOWED_TOPICS_FEED_LIMIT = 5
 )
 
+// personalized feed sources (?source= query param for authenticated users)
+const (
+	FeedSourceTopics  = "topics"
+	FeedSourceAuthors = "authors"
+	FeedSourceAll     = "all"
+)
+
 type CreateBlogRequest struct {
 	BlogTitle        string             `json:"blog_title"`
 	BlogDescription  string             `json:"blog_description"`
@@ -28,6 +35,15 @@ type CreateBlogRequest struct {
 	BlogTopicIds     []int              `json:"blog_topic_ids"`
 }
 
+// UpdateBlogRequest fields left out of the request body keep their current value
+type UpdateBlogRequest struct {
+	BlogTitle        *string         `json:"blog_title"`
+	BlogDescription  *string         `json:"blog_description"`
+	BlogContent      json.RawMessage `json:"blog_content"`
+	BlogThumbnailUrl *string         `json:"blog_thumbnail_url"`
+	BlogTopicIds     *[]int          `json:"blog_topic_ids"` // replaces the blog's topics when present
+}
+
 type UpdateBlogStatusRequest struct {
 	BlogStatus   storage.BlogStatus `json:"blog_status"`
 	BlogTopicIds []int              `json:"blog_topic_ids"` // optional additional topic ids that user might want to add while publishing a 'draft' blog
@@ -71,6 +87,12 @@ func (h *Handler) CreateBlogHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	//	a blog is scheduled through PUT /blog/{blogId}/schedule once it exists as a draft
+	if blogStatus == storage.BlogStatusScheduled {
+		writeJSONError(w, "cannot create a scheduled blog, create a draft and schedule it", http.StatusBadRequest)
+		return
+	}
+
 	if len(blogTopicIds) > MAX_TOPICS_PER_BLOG {
 		writeJSONError(w, fmt.Sprintf("a blog can have max %v no of topics", MAX_TOPICS_PER_BLOG), http.StatusBadRequest)
 		return
@@ -135,6 +157,90 @@ func (h *Handler) CreateBlogHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// single blog read (optional auth), drafts and archived blogs are only visible to their author
+func (h *Handler) GetBlogHandler(w http.ResponseWriter, r *http.Request) {
+
+	hasAuthUser := true
+	authUserId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		hasAuthUser = false
+	}
+
+	blogId, err := strconv.ParseInt(chi.URLParam(r, "blogId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param blogId", http.StatusBadRequest)
+		return
+	}
+
+	blog, err := h.storage.GetBlogWithMetaDataById(int(blogId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "blog does not exist", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to get blog by id: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	isBlogAuthor := hasAuthUser && authUserId == blog.BlogAuthorId
+
+	// non-published blogs are reported as not existing to everyone except the author
+	if blog.BlogStatus != storage.BlogStatusPublished && !isBlogAuthor {
+		writeJSONError(w, "blog does not exist", http.StatusBadRequest)
+		return
+	}
+
+	// so are the blogs of a suspended or banned author
+	if !isBlogAuthor {
+		_, err := h.storage.GetActiveUserSuspension(blog.BlogAuthorId)
+		if err == nil {
+			writeJSONError(w, "blog does not exist", http.StatusNotFound)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("failed to get author suspension: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	isLiked := false
+	isBookmarked := false
+
+	//	the viewer's likes and bookmarks are read scope data, a blogs:write only token just gets the blog
+	if hasAuthUser && HasTokenScope(r, TokenScopeRead) {
+
+		blogLike, err := h.storage.GetBlogLike(authUserId, blog.Id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		isLiked = blogLike != nil
+
+		blogBookmark, err := h.storage.GetBlogBookmark(authUserId, blog.Id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		isBookmarked = blogBookmark != nil
+	}
+
+	type Response struct {
+		Success      bool                     `json:"success"`
+		Blog         storage.BlogWithMetaData `json:"blog"`
+		IsLiked      bool                     `json:"is_liked"`
+		IsBookmarked bool                     `json:"is_bookmarked"`
+	}
+
+	w.Header().Set("ETag", blogETag(&blog.Blog))
+
+	if err := writeJSON(w, Response{Success: true, Blog: *blog, IsLiked: isLiked, IsBookmarked: isBookmarked}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
 func (h *Handler) DeleteBlogHandler(w http.ResponseWriter, r *http.Request) {
 
 	userId, ok := r.Context().Value(AuthUserId).(int)
@@ -176,7 +282,16 @@ func (h *Handler) DeleteBlogHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.storage.DeleteBlogById(blog.Id); err != nil {
+	if !isBlogIfMatchSatisfied(r, blog) {
+		h.writeBlogPreconditionFailed(w, blog.Id)
+		return
+	}
+
+	if err := h.storage.DeleteBlogById(blog.Id, blog.BlogVersion); err != nil {
+		if errors.Is(err, storage.ErrBlogVersionConflict) {
+			h.writeBlogPreconditionFailed(w, blog.Id)
+			return
+		}
 		log.Printf("failed to delete blog: %v\n", err)
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
@@ -192,7 +307,8 @@ func (h *Handler) DeleteBlogHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *Handler) UpdateBlogStatusHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateBlogHandler(w http.ResponseWriter, r *http.Request) {
+
 	userId, ok := r.Context().Value(AuthUserId).(int)
 	if !ok {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
@@ -226,82 +342,199 @@ func (h *Handler) UpdateBlogStatusHandler(w http.ResponseWriter, r *http.Request
 			return
 		}
 	}
+
 	if user.Id != blog.BlogAuthorId {
-		writeJSONError(w, "unauthorized to update blog status", http.StatusUnauthorized)
+		writeJSONError(w, "unauthorized to update blog", http.StatusUnauthorized)
 		return
 	}
-	//	if blog's status was 'draft' and changing to 'published' , 'blog should have topics and also should have feature to add extra
-	//  topics that were not added while creating blog as draft.
-	var updateBlogStatusPayload UpdateBlogStatusRequest
-	if err := json.NewDecoder(r.Body).Decode(&updateBlogStatusPayload); err != nil {
+
+	if !isBlogIfMatchSatisfied(r, blog) {
+		h.writeBlogPreconditionFailed(w, blog.Id)
+		return
+	}
+
+	var updateBlogPayload UpdateBlogRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&updateBlogPayload); err != nil {
 		writeJSONError(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	newBlogStatus := updateBlogStatusPayload.BlogStatus
-	additionalTopicIds := updateBlogStatusPayload.BlogTopicIds
-	for _, topicId := range additionalTopicIds {
-		_, err := h.storage.GetTopicById(topicId)
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				writeJSONError(w, "topic does not exist", http.StatusBadRequest)
-				return
-			} else {
-				writeJSONError(w, "internal server error", http.StatusInternalServerError)
-				return
-			}
+	//	start from the blog's current values and apply the fields present in the request
+	blogTitle := blog.BlogTitle
+	blogDescription := blog.BlogDescription
+	blogContentJson := blog.BlogContent
+	blogThumbnailUrl := blog.BlogThumbnail
+
+	if updateBlogPayload.BlogTitle != nil {
+		blogTitle = strings.TrimSpace(*updateBlogPayload.BlogTitle)
+		if blogTitle == "" {
+			writeJSONError(w, "blog title cannot be empty", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if updateBlogPayload.BlogDescription != nil {
+		newBlogDescription := strings.TrimSpace(*updateBlogPayload.BlogDescription)
+		blogDescription = &newBlogDescription
+	}
+
+	if len(updateBlogPayload.BlogContent) != 0 {
+		if string(updateBlogPayload.BlogContent) == "null" {
+			writeJSONError(w, "blog content cannot be empty", http.StatusBadRequest)
+			return
 		}
+		blogContentJson = updateBlogPayload.BlogContent
+	}
+
+	if updateBlogPayload.BlogThumbnailUrl != nil {
+		newBlogThumbnailUrl := strings.TrimSpace(*updateBlogPayload.BlogThumbnailUrl)
+		blogThumbnailUrl = &newBlogThumbnailUrl
 	}
 
-	if blog.BlogStatus == storage.BlogStatusDraft && newBlogStatus == storage.BlogStatusPublished {
+	var blogTopicIds []int
+
+	if updateBlogPayload.BlogTopicIds != nil {
+
+		blogTopicIds = *updateBlogPayload.BlogTopicIds
+
+		if len(blogTopicIds) > MAX_TOPICS_PER_BLOG {
+			writeJSONError(w, fmt.Sprintf("a blog can have max %v no of topics", MAX_TOPICS_PER_BLOG), http.StatusBadRequest)
+			return
+		}
+
+		for _, topicId := range blogTopicIds {
+			_, err := h.storage.GetTopicById(topicId)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					writeJSONError(w, "topic does not exist", http.StatusBadRequest)
+					return
+				} else {
+					writeJSONError(w, "internal server error", http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+	} else {
 
-		//	check if draft has any topics , if not then there better be additionalTopicIds to add to the draft and then publish
-		// if there are existing topics,  then check if there are additional topics, if yes . then check that additional topics do not include any of the blog's topics
 		existingBlogTopics, err := h.storage.GetBlogTopics(blog.Id)
 		if err != nil {
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
-		var existingBlogTopicIds []int
+
 		for _, existingBlogTopic := range existingBlogTopics {
-			existingBlogTopicIds = append(existingBlogTopicIds, existingBlogTopic.Id)
+			blogTopicIds = append(blogTopicIds, existingBlogTopic.Id)
 		}
+	}
 
-		var publishedBlog *storage.BlogWithUserAndTopics
+	if (blog.BlogStatus == storage.BlogStatusPublished || blog.BlogStatus == storage.BlogStatusScheduled) && len(blogTopicIds) == 0 {
+		writeJSONError(w, "blog topics compulsory for published or scheduled blog", http.StatusBadRequest)
+		return
+	}
 
-		if len(existingBlogTopics) > 0 {
-			//	check if any additional topic id exists in blogTopicIds
-			for _, topicId := range additionalTopicIds {
-				//	check if this topicId exists in existingBlogTopicIds
-				if isArrayContainElement(existingBlogTopicIds, topicId) {
-					writeJSONError(w, "topic already exists", http.StatusBadRequest)
-					return
-				}
-			}
+	updatedBlog, err := h.storage.UpdateBlog(blog.Id, blog.BlogVersion, blogTitle, blogDescription, blogContentJson, blogThumbnailUrl, blogTopicIds)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlogVersionConflict) {
+			h.writeBlogPreconditionFailed(w, blog.Id)
+			return
+		}
+		log.Printf("failed to update blog: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
 
-			//	if here then additional topic ids are valid and ready to be added to blog's topics
-			// update blog's status from 'draft' to 'published' and 'update blog's topics' -> do in one transaction
-			publishedBlog, err = h.storage.PublishBlogAndAddTopics(blog.Id, additionalTopicIds)
-			if err != nil {
-				log.Printf("failed to publish and add topics: %v\n", err)
-				writeJSONError(w, "internal server error", http.StatusInternalServerError)
-				return
-			}
+	type Response struct {
+		Success bool                          `json:"success"`
+		Message string                        `json:"message"`
+		Blog    storage.BlogWithUserAndTopics `json:"blog"`
+	}
+
+	w.Header().Set("ETag", blogETag(&updatedBlog.Blog))
+
+	if err := writeJSON(w, Response{Success: true, Message: "blog updated successfully", Blog: *updatedBlog}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) UpdateBlogStatusHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	blogId, err := strconv.ParseInt(chi.URLParam(r, "blogId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param blogId", http.StatusBadRequest)
+		return
+	}
+
+	blog, err := h.storage.GetBlogById(int(blogId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "blog does not exist", http.StatusBadRequest)
+			return
 		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if user.Id != blog.BlogAuthorId {
+		writeJSONError(w, "unauthorized to update blog status", http.StatusUnauthorized)
+		return
+	}
+	if !isBlogIfMatchSatisfied(r, blog) {
+		h.writeBlogPreconditionFailed(w, blog.Id)
+		return
+	}
+	//	if blog's status was 'draft' and changing to 'published' , 'blog should have topics and also should have feature to add extra
+	//  topics that were not added while creating blog as draft.
+	var updateBlogStatusPayload UpdateBlogStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&updateBlogStatusPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	newBlogStatus := updateBlogStatusPayload.BlogStatus
+	additionalTopicIds := updateBlogStatusPayload.BlogTopicIds
+
+	if (blog.BlogStatus == storage.BlogStatusDraft || blog.BlogStatus == storage.BlogStatusScheduled) && newBlogStatus == storage.BlogStatusPublished {
 
-			//	 no existing blogs so if additionTopicIds.length == 0 then throw error
-			if len(additionalTopicIds) == 0 {
-				writeJSONError(w, "topics required to publish blog", http.StatusBadRequest)
+		//	a scheduled blog can also be published right away (its schedule is dropped)
+		if err := h.validateBlogPublishTopics(blog.Id, additionalTopicIds); err != nil {
+			if isBlogPublishTopicsError(err) {
+				writeJSONError(w, err.Error(), http.StatusBadRequest)
+				return
+			} else {
+				log.Printf("failed to validate blog topics: %v\n", err)
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
 				return
 			}
+		}
 
-			//	so the additionalTopicIds are the all topicIds for this blog currently
-			publishedBlog, err = h.storage.PublishBlogAndAddTopics(blog.Id, additionalTopicIds)
-			if err != nil {
-				log.Printf("failed to publish and add topics: %v\n", err)
-				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		//	if here then additional topic ids are valid and ready to be added to blog's topics
+		// update blog's status to 'published' and 'update blog's topics' -> do in one transaction
+		publishedBlog, err := h.storage.PublishBlogAndAddTopics(blog.Id, blog.BlogVersion, additionalTopicIds)
+		if err != nil {
+			if errors.Is(err, storage.ErrBlogVersionConflict) {
+				h.writeBlogPreconditionFailed(w, blog.Id)
 				return
 			}
+			log.Printf("failed to publish and add topics: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
 		}
 
 		type Response struct {
@@ -310,14 +543,20 @@ func (h *Handler) UpdateBlogStatusHandler(w http.ResponseWriter, r *http.Request
 			Blog    storage.BlogWithUserAndTopics `json:"blog"`
 		}
 
+		w.Header().Set("ETag", blogETag(&publishedBlog.Blog))
+
 		if err := writeJSON(w, Response{Success: true, Message: "blog published successfully", Blog: *publishedBlog}, http.StatusOK); err != nil {
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
 	} else if blog.BlogStatus == storage.BlogStatusPublished && newBlogStatus == storage.BlogStatusArchived {
 
-		archivedBlog, err := h.storage.UpdateBlogStatus(int(blog.Id), newBlogStatus)
+		archivedBlog, err := h.storage.UpdateBlogStatus(int(blog.Id), blog.BlogVersion, newBlogStatus)
 		if err != nil {
+			if errors.Is(err, storage.ErrBlogVersionConflict) {
+				h.writeBlogPreconditionFailed(w, blog.Id)
+				return
+			}
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
@@ -328,6 +567,8 @@ func (h *Handler) UpdateBlogStatusHandler(w http.ResponseWriter, r *http.Request
 			Blog    storage.BlogWithUserAndTopics `json:"blog"`
 		}
 
+		w.Header().Set("ETag", blogETag(&archivedBlog.Blog))
+
 		if err := writeJSON(w, Response{Success: true, Message: "blog archived successfully", Blog: *archivedBlog}, http.StatusOK); err != nil {
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
@@ -335,8 +576,12 @@ func (h *Handler) UpdateBlogStatusHandler(w http.ResponseWriter, r *http.Request
 	} else if blog.BlogStatus == storage.BlogStatusArchived && newBlogStatus == storage.BlogStatusPublished {
 
 		//	an archived blog means once it was published (so it has topics already)
-		publishedBlog, err := h.storage.UpdateBlogStatus(int(blog.Id), newBlogStatus)
+		publishedBlog, err := h.storage.UpdateBlogStatus(int(blog.Id), blog.BlogVersion, newBlogStatus)
 		if err != nil {
+			if errors.Is(err, storage.ErrBlogVersionConflict) {
+				h.writeBlogPreconditionFailed(w, blog.Id)
+				return
+			}
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
@@ -347,6 +592,8 @@ func (h *Handler) UpdateBlogStatusHandler(w http.ResponseWriter, r *http.Request
 			Blog    storage.BlogWithUserAndTopics `json:"blog"`
 		}
 
+		w.Header().Set("ETag", blogETag(&publishedBlog.Blog))
+
 		if err := writeJSON(w, Response{Success: true, Message: "blog published successfully", Blog: *publishedBlog}, http.StatusOK); err != nil {
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
@@ -585,6 +832,7 @@ func (h *Handler) GetBlogsFeedByTopicHandler(w http.ResponseWriter, r *http.Requ
 }
 
 // main blogs feed for unauthenticated user's (blogs with topics being the top n'th most followed topics)
+// authenticated user's get blogs from their followed topics and/or followed authors (?source=topics|authors|all)
 func (h *Handler) GetBlogsFeedHandler(w http.ResponseWriter, r *http.Request) {
 	hasAuthUser := true
 	authUserId, ok := r.Context().Value(AuthUserId).(int)
@@ -633,19 +881,59 @@ func (h *Handler) GetBlogsFeedHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		//	get blogs for the feed consisting of blogs where topics of those blogs are followed by user
-
-		blogs, err = h.storage.GetBlogsByUserFollowedTopics(authUser.Id, skip, limit)
-		if err != nil {
-			log.Printf("failed to get blogs by user followed topics: %v\n", err)
-			writeJSONError(w, "internal server error", http.StatusInternalServerError)
-			return
+		feedSource := r.URL.Query().Get("source")
+		if feedSource == "" {
+			feedSource = FeedSourceAll
 		}
 
-		totalBlogsCount, err = h.storage.GetBlogsByUserFollowedTopicsCount(authUser.Id)
-		if err != nil {
-			log.Printf("failed to get blogs count by user followed topics: %v\n", err)
-			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		switch feedSource {
+		case FeedSourceTopics:
+			//	get blogs for the feed consisting of blogs where topics of those blogs are followed by user
+			blogs, err = h.storage.GetBlogsByUserFollowedTopics(authUser.Id, skip, limit)
+			if err != nil {
+				log.Printf("failed to get blogs by user followed topics: %v\n", err)
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			totalBlogsCount, err = h.storage.GetBlogsByUserFollowedTopicsCount(authUser.Id)
+			if err != nil {
+				log.Printf("failed to get blogs count by user followed topics: %v\n", err)
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		case FeedSourceAuthors:
+			//	blogs written by authors that the user follows
+			blogs, err = h.storage.GetBlogsByUserFollowedAuthors(authUser.Id, skip, limit)
+			if err != nil {
+				log.Printf("failed to get blogs by user followed authors: %v\n", err)
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			totalBlogsCount, err = h.storage.GetBlogsByUserFollowedAuthorsCount(authUser.Id)
+			if err != nil {
+				log.Printf("failed to get blogs count by user followed authors: %v\n", err)
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		case FeedSourceAll:
+			//	blogs from followed topics and followed authors (a blog matching both appears once)
+			blogs, err = h.storage.GetBlogsByUserFollowedTopicsAndAuthors(authUser.Id, skip, limit)
+			if err != nil {
+				log.Printf("failed to get blogs by user followed topics and authors: %v\n", err)
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			totalBlogsCount, err = h.storage.GetBlogsByUserFollowedTopicsAndAuthorsCount(authUser.Id)
+			if err != nil {
+				log.Printf("failed to get blogs count by user followed topics and authors: %v\n", err)
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		default:
+			writeJSONError(w, "invalid query param source, expected topics, authors or all", http.StatusBadRequest)
 			return
 		}
 	} else {
@@ -677,6 +965,60 @@ func (h *Handler) GetBlogsFeedHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+var (
+	errBlogTopicDoesNotExist   = errors.New("topic does not exist")
+	errBlogTopicAlreadyExists  = errors.New("topic already exists")
+	errBlogTopicsRequired      = errors.New("topics required to publish blog")
+	errBlogTopicsLimitExceeded = fmt.Errorf("a blog can have max %v no of topics", MAX_TOPICS_PER_BLOG)
+)
+
+func isBlogPublishTopicsError(err error) bool {
+	return errors.Is(err, errBlogTopicDoesNotExist) || errors.Is(err, errBlogTopicAlreadyExists) ||
+		errors.Is(err, errBlogTopicsRequired) || errors.Is(err, errBlogTopicsLimitExceeded)
+}
+
+// validateBlogPublishTopics checks that a blog can be published (now or scheduled) with additionalTopicIds added to it:
+// the additional topics have to exist and not already be blog topics, and the blog needs at least one topic overall
+func (h *Handler) validateBlogPublishTopics(blogId int, additionalTopicIds []int) error {
+
+	for _, topicId := range additionalTopicIds {
+		_, err := h.storage.GetTopicById(topicId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errBlogTopicDoesNotExist
+			}
+			return err
+		}
+	}
+
+	//	check if draft has any topics , if not then there better be additionalTopicIds to add to the draft and then publish
+	// if there are existing topics,  then check if there are additional topics, if yes . then check that additional topics do not include any of the blog's topics
+	existingBlogTopics, err := h.storage.GetBlogTopics(blogId)
+	if err != nil {
+		return err
+	}
+	var existingBlogTopicIds []int
+	for _, existingBlogTopic := range existingBlogTopics {
+		existingBlogTopicIds = append(existingBlogTopicIds, existingBlogTopic.Id)
+	}
+
+	for _, topicId := range additionalTopicIds {
+		if isArrayContainElement(existingBlogTopicIds, topicId) {
+			return errBlogTopicAlreadyExists
+		}
+	}
+
+	if len(existingBlogTopicIds)+len(additionalTopicIds) == 0 {
+		return errBlogTopicsRequired
+	}
+
+	if len(existingBlogTopicIds)+len(additionalTopicIds) > MAX_TOPICS_PER_BLOG {
+		return errBlogTopicsLimitExceeded
+	}
+
+	return nil
+}
+
 func isArrayContainElement(arr []int, target int) bool {
 
 	for _, val := range arr {
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// FeatureBlogHandler requires the blog:feature permission, only published blogs can be featured
+func (h *Handler) FeatureBlogHandler(w http.ResponseWriter, r *http.Request) {
+
+	blogId, err := strconv.ParseInt(chi.URLParam(r, "blogId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param blogId", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.FeatureBlog(int(blogId)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "published blog not found", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to feature blog: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "blog featured"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// UnfeatureBlogHandler requires the blog:feature permission
+func (h *Handler) UnfeatureBlogHandler(w http.ResponseWriter, r *http.Request) {
+
+	blogId, err := strconv.ParseInt(chi.URLParam(r, "blogId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param blogId", http.StatusBadRequest)
+		return
+	}
+
+	blog, err := h.storage.GetBlogById(int(blogId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "blog not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := h.storage.UnfeatureBlog(blog.Id); err != nil {
+		log.Printf("failed to unfeature blog: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "blog unfeatured"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// GetFeaturedBlogsHandler - unauthenticated, most recently featured first
+func (h *Handler) GetFeaturedBlogsHandler(w http.ResponseWriter, r *http.Request) {
+
+	var page int
+	var limit int
+	var err error
+
+	if r.URL.Query().Get("page") == "" {
+		page = 1
+	} else {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+			return
+		}
+	}
+	if r.URL.Query().Get("limit") == "" {
+		limit = 10
+	} else {
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil {
+			writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	skip := page*limit - limit
+
+	blogs, err := h.storage.GetFeaturedBlogs(skip, limit)
+	if err != nil {
+		log.Printf("failed to get featured blogs: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalBlogsCount, err := h.storage.GetFeaturedBlogsCount()
+	if err != nil {
+		log.Printf("failed to get featured blogs count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalBlogsCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool                   `json:"success"`
+		Blogs     []storage.FeaturedBlog `json:"blogs"`
+		NoOfPages int                    `json:"no_of_pages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Blogs: blogs, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
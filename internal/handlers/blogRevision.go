@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// blog revisions are only visible to the blog's author
+
+func (h *Handler) GetBlogRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	blogId, err := strconv.ParseInt(chi.URLParam(r, "blogId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param blogId", http.StatusBadRequest)
+		return
+	}
+
+	blog, err := h.storage.GetBlogById(int(blogId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "blog does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if userId != blog.BlogAuthorId {
+		writeJSONError(w, "unauthorized to view blog revisions", http.StatusUnauthorized)
+		return
+	}
+
+	var page int
+	var limit int
+
+	if r.URL.Query().Get("page") == "" {
+		page = 1
+	} else {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+			return
+		}
+	}
+	if r.URL.Query().Get("limit") == "" {
+		limit = 10
+	} else {
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil {
+			writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	skip := page*limit - limit
+
+	blogRevisions, err := h.storage.GetBlogRevisions(blog.Id, skip, limit)
+	if err != nil {
+		log.Printf("failed to get blog revisions: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalBlogRevisionsCount, err := h.storage.GetBlogRevisionsCount(blog.Id)
+	if err != nil {
+		log.Printf("failed to get blog revisions count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalBlogRevisionsCount) / float64(limit)))
+
+	type Response struct {
+		Success       bool                   `json:"success"`
+		BlogRevisions []storage.BlogRevision `json:"blog_revisions"`
+		NoOfPages     int                    `json:"no_of_pages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, BlogRevisions: blogRevisions, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) GetBlogRevisionHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	blogId, err := strconv.ParseInt(chi.URLParam(r, "blogId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param blogId", http.StatusBadRequest)
+		return
+	}
+
+	revisionNumber, err := strconv.ParseInt(chi.URLParam(r, "revisionNumber"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param revisionNumber", http.StatusBadRequest)
+		return
+	}
+
+	blog, err := h.storage.GetBlogById(int(blogId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "blog does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if userId != blog.BlogAuthorId {
+		writeJSONError(w, "unauthorized to view blog revisions", http.StatusUnauthorized)
+		return
+	}
+
+	blogRevision, err := h.storage.GetBlogRevision(blog.Id, int(revisionNumber))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "blog revision does not exist", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to get blog revision: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success      bool                 `json:"success"`
+		BlogRevision storage.BlogRevision `json:"blog_revision"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, BlogRevision: *blogRevision}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// restoring a revision saves its fields as a new edit (and so a new revision), history is never rewritten
+func (h *Handler) RestoreBlogRevisionHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	blogId, err := strconv.ParseInt(chi.URLParam(r, "blogId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param blogId", http.StatusBadRequest)
+		return
+	}
+
+	revisionNumber, err := strconv.ParseInt(chi.URLParam(r, "revisionNumber"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param revisionNumber", http.StatusBadRequest)
+		return
+	}
+
+	blog, err := h.storage.GetBlogById(int(blogId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "blog does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if userId != blog.BlogAuthorId {
+		writeJSONError(w, "unauthorized to restore blog revision", http.StatusUnauthorized)
+		return
+	}
+
+	if !isBlogIfMatchSatisfied(r, blog) {
+		h.writeBlogPreconditionFailed(w, blog.Id)
+		return
+	}
+
+	blogRevision, err := h.storage.GetBlogRevision(blog.Id, int(revisionNumber))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "blog revision does not exist", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to get blog revision: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	//	topics deleted since the revision was saved are dropped
+	var blogTopicIds []int
+	for _, topicId := range blogRevision.BlogTopicIds {
+		_, err := h.storage.GetTopicById(int(topicId))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			} else {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+		blogTopicIds = append(blogTopicIds, int(topicId))
+	}
+
+	if (blog.BlogStatus == storage.BlogStatusPublished || blog.BlogStatus == storage.BlogStatusScheduled) && len(blogTopicIds) == 0 {
+		writeJSONError(w, "blog topics compulsory for published or scheduled blog, revision topics no longer exist", http.StatusBadRequest)
+		return
+	}
+
+	restoredBlog, err := h.storage.UpdateBlog(blog.Id, blog.BlogVersion, blogRevision.BlogTitle, blogRevision.BlogDescription, blogRevision.BlogContent, blogRevision.BlogThumbnail, blogTopicIds)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlogVersionConflict) {
+			h.writeBlogPreconditionFailed(w, blog.Id)
+			return
+		}
+		log.Printf("failed to restore blog revision: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool                          `json:"success"`
+		Message string                        `json:"message"`
+		Blog    storage.BlogWithUserAndTopics `json:"blog"`
+	}
+
+	w.Header().Set("ETag", blogETag(&restoredBlog.Blog))
+
+	if err := writeJSON(w, Response{Success: true, Message: "blog revision restored successfully", Blog: *restoredBlog}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
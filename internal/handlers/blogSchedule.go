@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ScheduleBlogRequest struct {
+	PublishAt    string `json:"publish_at"`     // RFC3339 timestamp in the future
+	BlogTopicIds []int  `json:"blog_topic_ids"` // optional additional topic ids, same as when publishing a draft
+}
+
+// ScheduleBlogHandler schedules a draft (or reschedules a scheduled blog), cmd/blogScheduler publishes it at publish_at
+func (h *Handler) ScheduleBlogHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	blogId, err := strconv.ParseInt(chi.URLParam(r, "blogId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param blogId", http.StatusBadRequest)
+		return
+	}
+
+	blog, err := h.storage.GetBlogById(int(blogId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "blog does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if userId != blog.BlogAuthorId {
+		writeJSONError(w, "unauthorized to schedule blog", http.StatusUnauthorized)
+		return
+	}
+
+	if !isBlogIfMatchSatisfied(r, blog) {
+		h.writeBlogPreconditionFailed(w, blog.Id)
+		return
+	}
+
+	if blog.BlogStatus != storage.BlogStatusDraft && blog.BlogStatus != storage.BlogStatusScheduled {
+		writeJSONError(w, fmt.Sprintf("cannot schedule blog with current blog status %v", blog.BlogStatus), http.StatusBadRequest)
+		return
+	}
+
+	var scheduleBlogPayload ScheduleBlogRequest
+	if err := json.NewDecoder(r.Body).Decode(&scheduleBlogPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	publishAt, err := time.Parse(time.RFC3339, scheduleBlogPayload.PublishAt)
+	if err != nil {
+		writeJSONError(w, "invalid publish_at, expected RFC3339 timestamp", http.StatusBadRequest)
+		return
+	}
+
+	if !publishAt.After(time.Now()) {
+		writeJSONError(w, "publish_at should be in the future", http.StatusBadRequest)
+		return
+	}
+
+	additionalTopicIds := scheduleBlogPayload.BlogTopicIds
+
+	if err := h.validateBlogPublishTopics(blog.Id, additionalTopicIds); err != nil {
+		if isBlogPublishTopicsError(err) {
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to validate blog topics: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	//	TIMESTAMP columns hold the server's local time (time.Now()), publish_at is copied into published_at
+	scheduledBlog, err := h.storage.ScheduleBlogAndAddTopics(blog.Id, blog.BlogVersion, publishAt.Local(), additionalTopicIds)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlogVersionConflict) {
+			h.writeBlogPreconditionFailed(w, blog.Id)
+			return
+		}
+		log.Printf("failed to schedule blog: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool                          `json:"success"`
+		Message string                        `json:"message"`
+		Blog    storage.BlogWithUserAndTopics `json:"blog"`
+	}
+
+	w.Header().Set("ETag", blogETag(&scheduledBlog.Blog))
+
+	if err := writeJSON(w, Response{Success: true, Message: "blog scheduled successfully", Blog: *scheduledBlog}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// CancelBlogScheduleHandler moves a scheduled blog back to draft, topics added while scheduling are kept
+func (h *Handler) CancelBlogScheduleHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	blogId, err := strconv.ParseInt(chi.URLParam(r, "blogId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param blogId", http.StatusBadRequest)
+		return
+	}
+
+	blog, err := h.storage.GetBlogById(int(blogId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "blog does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if userId != blog.BlogAuthorId {
+		writeJSONError(w, "unauthorized to cancel blog schedule", http.StatusUnauthorized)
+		return
+	}
+
+	if !isBlogIfMatchSatisfied(r, blog) {
+		h.writeBlogPreconditionFailed(w, blog.Id)
+		return
+	}
+
+	if blog.BlogStatus != storage.BlogStatusScheduled {
+		writeJSONError(w, "blog is not scheduled", http.StatusBadRequest)
+		return
+	}
+
+	//	the version guard also covers the scheduler publishing the blog in the meantime
+	draftBlog, err := h.storage.UpdateBlogStatus(blog.Id, blog.BlogVersion, storage.BlogStatusDraft)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlogVersionConflict) {
+			h.writeBlogPreconditionFailed(w, blog.Id)
+			return
+		}
+		log.Printf("failed to cancel blog schedule: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool                          `json:"success"`
+		Message string                        `json:"message"`
+		Blog    storage.BlogWithUserAndTopics `json:"blog"`
+	}
+
+	w.Header().Set("ETag", blogETag(&draftBlog.Blog))
+
+	if err := writeJSON(w, Response{Success: true, Message: "blog schedule cancelled successfully", Blog: *draftBlog}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
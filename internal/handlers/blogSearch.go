@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SearchBlogsHandler full text search over published blogs
+// query params: q (required), topic_id, author_id (optional filters), page, limit
+func (h *Handler) SearchBlogsHandler(w http.ResponseWriter, r *http.Request) {
+
+	searchText := strings.TrimSpace(r.URL.Query().Get("q"))
+	if searchText == "" {
+		writeJSONError(w, "query param q is required", http.StatusBadRequest)
+		return
+	}
+
+	var topicId *int
+	var authorId *int
+
+	if r.URL.Query().Get("topic_id") != "" {
+		topicIdNum, err := strconv.Atoi(r.URL.Query().Get("topic_id"))
+		if err != nil {
+			writeJSONError(w, "invalid query param topic_id", http.StatusBadRequest)
+			return
+		}
+		topicId = &topicIdNum
+	}
+
+	if r.URL.Query().Get("author_id") != "" {
+		authorIdNum, err := strconv.Atoi(r.URL.Query().Get("author_id"))
+		if err != nil {
+			writeJSONError(w, "invalid query param author_id", http.StatusBadRequest)
+			return
+		}
+		authorId = &authorIdNum
+	}
+
+	var page int
+	var limit int
+	var err error
+
+	if r.URL.Query().Get("page") == "" {
+		page = 1
+	} else {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+			return
+		}
+	}
+	if r.URL.Query().Get("limit") == "" {
+		limit = 10
+	} else {
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil {
+			writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	skip := page*limit - limit
+
+	blogs, err := h.storage.SearchBlogs(searchText, topicId, authorId, skip, limit)
+	if err != nil {
+		log.Printf("failed to search blogs: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalBlogsCount, err := h.storage.SearchBlogsCount(searchText, topicId, authorId)
+	if err != nil {
+		log.Printf("failed to get search blogs count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalBlogsCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool                       `json:"success"`
+		Blogs     []storage.BlogSearchResult `json:"blogs"`
+		NoOfPages int                        `json:"no_of_pages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Blogs: blogs, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
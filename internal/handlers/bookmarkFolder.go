@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	MAX_BOOKMARK_FOLDER_NAME_LENGTH = 50
+)
+
+type BookmarkFolderRequest struct {
+	FolderName string `json:"folder_name"`
+}
+
+type MoveBookmarkRequest struct {
+	BookmarkFolderId *int `json:"bookmark_folder_id"` // null moves the bookmark out of its folder
+}
+
+func (h *Handler) GetMyBookmarkFoldersHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	bookmarkFolders, err := h.storage.GetUserBookmarkFolders(userId)
+	if err != nil {
+		log.Printf("failed to get bookmark folders: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success         bool                     `json:"success"`
+		BookmarkFolders []storage.BookmarkFolder `json:"bookmark_folders"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, BookmarkFolders: bookmarkFolders}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) CreateBookmarkFolderHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var bookmarkFolderPayload BookmarkFolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&bookmarkFolderPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	folderName := strings.TrimSpace(bookmarkFolderPayload.FolderName)
+
+	if errMessage := validateBookmarkFolderName(folderName); errMessage != "" {
+		writeJSONError(w, errMessage, http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.storage.GetBookmarkFolderByName(user.Id, folderName)
+	if err == nil {
+		writeJSONError(w, "bookmark folder already exists", http.StatusBadRequest)
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	bookmarkFolder, err := h.storage.CreateBookmarkFolder(user.Id, folderName)
+	if err != nil {
+		log.Printf("failed to create bookmark folder: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success        bool                   `json:"success"`
+		Message        string                 `json:"message"`
+		BookmarkFolder storage.BookmarkFolder `json:"bookmark_folder"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "bookmark folder created", BookmarkFolder: *bookmarkFolder}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// rename a bookmark folder
+func (h *Handler) UpdateBookmarkFolderHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	bookmarkFolderId, err := strconv.ParseInt(chi.URLParam(r, "bookmarkFolderId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param bookmarkFolderId", http.StatusBadRequest)
+		return
+	}
+
+	bookmarkFolder, err := h.storage.GetBookmarkFolderById(int(bookmarkFolderId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "bookmark folder does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if bookmarkFolder.UserId != userId {
+		writeJSONError(w, "unauthorized to update bookmark folder", http.StatusUnauthorized)
+		return
+	}
+
+	var bookmarkFolderPayload BookmarkFolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&bookmarkFolderPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	folderName := strings.TrimSpace(bookmarkFolderPayload.FolderName)
+
+	if errMessage := validateBookmarkFolderName(folderName); errMessage != "" {
+		writeJSONError(w, errMessage, http.StatusBadRequest)
+		return
+	}
+
+	existingBookmarkFolder, err := h.storage.GetBookmarkFolderByName(userId, folderName)
+	if err == nil && existingBookmarkFolder.Id != bookmarkFolder.Id {
+		writeJSONError(w, "bookmark folder already exists", http.StatusBadRequest)
+		return
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	updatedBookmarkFolder, err := h.storage.UpdateBookmarkFolderName(bookmarkFolder.Id, folderName)
+	if err != nil {
+		log.Printf("failed to update bookmark folder: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success        bool                   `json:"success"`
+		Message        string                 `json:"message"`
+		BookmarkFolder storage.BookmarkFolder `json:"bookmark_folder"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "bookmark folder updated", BookmarkFolder: *updatedBookmarkFolder}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// deleting a folder does not remove its bookmarks, they become unfiled
+func (h *Handler) DeleteBookmarkFolderHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	bookmarkFolderId, err := strconv.ParseInt(chi.URLParam(r, "bookmarkFolderId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param bookmarkFolderId", http.StatusBadRequest)
+		return
+	}
+
+	bookmarkFolder, err := h.storage.GetBookmarkFolderById(int(bookmarkFolderId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "bookmark folder does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if bookmarkFolder.UserId != userId {
+		writeJSONError(w, "unauthorized to delete bookmark folder", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.storage.DeleteBookmarkFolder(bookmarkFolder.Id); err != nil {
+		log.Printf("failed to delete bookmark folder: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "bookmark folder deleted"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// MoveBookmarkHandler files the authenticated user's bookmark of {blogId} into a bookmark folder
+func (h *Handler) MoveBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	blogId, err := strconv.ParseInt(chi.URLParam(r, "blogId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param blogId", http.StatusBadRequest)
+		return
+	}
+
+	var moveBookmarkPayload MoveBookmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&moveBookmarkPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if moveBookmarkPayload.BookmarkFolderId != nil {
+		bookmarkFolder, err := h.storage.GetBookmarkFolderById(*moveBookmarkPayload.BookmarkFolderId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSONError(w, "bookmark folder does not exist", http.StatusBadRequest)
+				return
+			} else {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if bookmarkFolder.UserId != userId {
+			writeJSONError(w, "unauthorized to use bookmark folder", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	blogBookmark, err := h.storage.UpdateBlogBookmarkFolder(userId, int(blogId), moveBookmarkPayload.BookmarkFolderId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "blog bookmark does not exist", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to move blog bookmark: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success      bool                 `json:"success"`
+		Message      string               `json:"message"`
+		BlogBookmark storage.BlogBookmark `json:"blog_bookmark"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "blog bookmark moved", BlogBookmark: *blogBookmark}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// validateBookmarkFolderName returns the error message for an invalid folder name, "" if it is valid
+func validateBookmarkFolderName(folderName string) string {
+
+	if folderName == "" {
+		return "folder name is required"
+	}
+
+	if len([]rune(folderName)) > MAX_BOOKMARK_FOLDER_NAME_LENGTH {
+		return fmt.Sprintf("folder name can have max %v characters", MAX_BOOKMARK_FOLDER_NAME_LENGTH)
+	}
+
+	return ""
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// ClientIpConfig where the client's ip address comes from when the api runs behind reverse proxies.
+// ForwardedHeader (e.g. X-Forwarded-For, X-Real-IP) is only read when the request comes from one of
+// TrustedProxies, otherwise anyone could pick the address their requests are counted under
+type ClientIpConfig struct {
+	TrustedProxies  []netip.Prefix
+	ForwardedHeader string
+}
+
+func (c ClientIpConfig) isTrustedProxy(addr netip.Addr) bool {
+	return slices.ContainsFunc(c.TrustedProxies, func(prefix netip.Prefix) bool {
+		return prefix.Contains(addr)
+	})
+}
+
+// clientIpAddress the peer address (RemoteAddr), or when the peer is a trusted proxy the right-most
+// address in the forwarded header that is not a trusted proxy itself (entries further left can be
+// spoofed by the client)
+func (h *Handler) clientIpAddress(r *http.Request) string {
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	peerAddr, err := netip.ParseAddr(host)
+	if err != nil || h.clientIp.ForwardedHeader == "" || !h.clientIp.isTrustedProxy(peerAddr.Unmap()) {
+		return host
+	}
+
+	//	a header repeated by several proxies counts as one comma separated list
+	forwardedAddrs := strings.Split(strings.Join(r.Header.Values(h.clientIp.ForwardedHeader), ","), ",")
+
+	clientAddr := peerAddr.Unmap()
+
+	for i := len(forwardedAddrs) - 1; i >= 0; i-- {
+
+		forwardedAddr, err := netip.ParseAddr(strings.TrimSpace(forwardedAddrs[i]))
+		if err != nil {
+			//	garbage from the client, the last trusted hop is the best we know
+			break
+		}
+
+		clientAddr = forwardedAddr.Unmap()
+		if !h.clientIp.isTrustedProxy(clientAddr) {
+			break
+		}
+	}
+
+	return clientAddr.String()
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIpAddress(t *testing.T) {
+
+	trustedProxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name            string
+		forwardedHeader string
+		remoteAddr      string
+		headerValues    []string
+		clientIp        string
+	}{
+		{"untrusted peer with forwarded header", "X-Forwarded-For", "8.8.8.8:1234", []string{"1.2.3.4"}, "8.8.8.8"},
+		{"trusted peer without forwarded header", "X-Forwarded-For", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"trusted peer", "X-Forwarded-For", "10.0.0.1:1234", []string{"6.6.6.6, 7.7.7.7"}, "7.7.7.7"},
+		{"chain of trusted proxies", "X-Forwarded-For", "10.0.0.1:1234", []string{"6.6.6.6, 7.7.7.7, 10.0.0.3, 10.0.0.2"}, "7.7.7.7"},
+		{"header repeated by several proxies", "X-Forwarded-For", "10.0.0.1:1234", []string{"7.7.7.7", "10.0.0.2"}, "7.7.7.7"},
+		{"garbage entry", "X-Forwarded-For", "10.0.0.1:1234", []string{"junk, 10.0.0.2"}, "10.0.0.2"},
+		{"garbage entry left of the client", "X-Forwarded-For", "10.0.0.1:1234", []string{"junk, 7.7.7.7, 10.0.0.2"}, "7.7.7.7"},
+		{"only trusted proxies", "X-Forwarded-For", "10.0.0.1:1234", []string{"10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"ipv4-mapped ipv6 trusted peer", "X-Forwarded-For", "[::ffff:10.0.0.1]:1234", []string{"8.8.8.8"}, "8.8.8.8"},
+		{"ipv4-mapped ipv6 untrusted peer", "X-Forwarded-For", "[::ffff:8.8.8.8]:1234", []string{"1.2.3.4"}, "::ffff:8.8.8.8"},
+		{"ipv4-mapped ipv6 forwarded entry", "X-Forwarded-For", "10.0.0.1:1234", []string{"::ffff:7.7.7.7, ::ffff:10.0.0.2"}, "7.7.7.7"},
+		{"no forwarded header configured", "", "10.0.0.1:1234", []string{"7.7.7.7"}, "10.0.0.1"},
+		{"x-real-ip", "X-Real-IP", "10.0.0.1:1234", []string{"7.7.7.7"}, "7.7.7.7"},
+	}
+
+	for _, test := range tests {
+
+		h := &Handler{clientIp: ClientIpConfig{TrustedProxies: trustedProxies, ForwardedHeader: test.forwardedHeader}}
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = test.remoteAddr
+		for _, headerValue := range test.headerValues {
+			r.Header.Add("X-Forwarded-For", headerValue)
+			r.Header.Add("X-Real-IP", headerValue)
+		}
+
+		if clientIp := h.clientIpAddress(r); clientIp != test.clientIp {
+			t.Errorf("%s: client ip = %s, want %s", test.name, clientIp, test.clientIp)
+		}
+	}
+}
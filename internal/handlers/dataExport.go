@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// exports per user within the rate limit window, an export reads every row the user owns
+	MAX_DATA_EXPORT_REQUESTS          = 5
+	DATA_EXPORT_RATE_LIMIT_WINDOW     = time.Hour
+	DATA_EXPORT_RATE_LIMIT_KEY_PREFIX = "data_export_requests:"
+)
+
+// ExportMeHandler responds with a zip of the authenticated user's data, one json file per kind of data
+func (h *Handler) ExportMeHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	isAllowed, err := h.allowRateLimitedRequest(DATA_EXPORT_RATE_LIMIT_KEY_PREFIX+strconv.Itoa(userId), MAX_DATA_EXPORT_REQUESTS, DATA_EXPORT_RATE_LIMIT_WINDOW)
+	if err != nil {
+		log.Printf("failed to check data export rate limit: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isAllowed {
+		writeJSONError(w, "too many data export requests, please try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	userDataExport, err := h.storage.GetUserDataExport(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to get user data export: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	//	the zip is built in memory first so that a failure can still be answered with a json error
+	zipBuf, err := buildDataExportZip(userDataExport)
+	if err != nil {
+		log.Printf("failed to build data export zip: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	fileName := fmt.Sprintf("go-blog-app-export-%d-%s.zip", userId, time.Now().Format("20060102"))
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	w.Header().Set("Content-Length", strconv.Itoa(zipBuf.Len()))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := zipBuf.WriteTo(w); err != nil {
+		log.Printf("failed to write data export zip: %v\n", err)
+	}
+}
+
+func buildDataExportZip(userDataExport *storage.UserDataExport) (*bytes.Buffer, error) {
+
+	type Profile struct {
+		User       storage.User           `json:"user"`
+		Identities []storage.UserIdentity `json:"identities"`
+	}
+
+	type Likes struct {
+		BlogLikes        []storage.BlogLike        `json:"blog_likes"`
+		BlogCommentLikes []storage.BlogCommentLike `json:"blog_comment_likes"`
+	}
+
+	type Bookmarks struct {
+		BookmarkFolders []storage.BookmarkFolder `json:"bookmark_folders"`
+		BlogBookmarks   []storage.BlogBookmark   `json:"blog_bookmarks"`
+	}
+
+	exportFiles := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", Profile{User: userDataExport.User, Identities: userDataExport.Identities}},
+		{"blogs.json", userDataExport.Blogs},
+		{"comments.json", userDataExport.BlogComments},
+		{"likes.json", Likes{BlogLikes: userDataExport.BlogLikes, BlogCommentLikes: userDataExport.BlogCommentLikes}},
+		{"bookmarks.json", Bookmarks{BookmarkFolders: userDataExport.BookmarkFolders, BlogBookmarks: userDataExport.BlogBookmarks}},
+		{"topic_follows.json", userDataExport.TopicFollows},
+	}
+
+	var zipBuf bytes.Buffer
+	zipWriter := zip.NewWriter(&zipBuf)
+
+	for _, exportFile := range exportFiles {
+
+		fileWriter, err := zipWriter.Create(exportFile.name)
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := json.NewEncoder(fileWriter)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(exportFile.data); err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", exportFile.name, err)
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return &zipBuf, nil
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+)
+
+type EmailType string
+
+const (
+	EMAILS_QUEUE                         = "emails"
+	MAX_REDIS_QUEUE_RETRIES              = 3
+	EmailTypeVerification      EmailType = "verification"
+	EmailTypePasswordReset     EmailType = "password_reset"
+	EmailTypeMagicLink         EmailType = "magic_link"
+	EmailTypeEmailChange       EmailType = "email_change"
+	EmailTypeEmailChangeNotice EmailType = "email_change_notice"
+	EmailTypeSuspiciousLogin   EmailType = "suspicious_login"
+)
+
+// EmailData is the job pushed onto the emails queue, the emailsWorker picks the template
+// to render from EmailType (fields not used by a template are left empty)
+type EmailData struct {
+	EmailType        EmailType `json:"email_type"`
+	Subject          string    `json:"subject"`
+	Email            string    `json:"email"`
+	ActivationUrl    string    `json:"activation_url,omitempty"`
+	ResetPasswordUrl string    `json:"reset_password_url,omitempty"`
+	MagicLinkUrl     string    `json:"magic_link_url,omitempty"`
+	EmailChangeUrl   string    `json:"email_change_url,omitempty"`
+	NewEmail         string    `json:"new_email,omitempty"`
+	IpAddress        string    `json:"ip_address,omitempty"`
+	FailedAttempts   int       `json:"failed_attempts,omitempty"`
+}
+
+// push this job(email job) onto the emails job queue (to be processed by background worker)
+func (h *Handler) pushEmailJob(emailData EmailData) error {
+
+	emailDataJsonBytes, err := json.Marshal(emailData)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < MAX_REDIS_QUEUE_RETRIES; i++ {
+		if err = h.redisClient.LPush(context.Background(), EMAILS_QUEUE, string(emailDataJsonBytes)).Err(); err != nil {
+			log.Printf("failed to push email data to redis queue, attempt:%d\n", i+1)
+			continue
+		}
+		return nil
+	}
+
+	return err
+}
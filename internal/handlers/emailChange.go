@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email"`
+	Password string `json:"password"`
+}
+
+const (
+	EMAIL_CHANGE_EXPIRATION = time.Hour
+	// email change requests per user within the rate limit window
+	MAX_EMAIL_CHANGE_REQUESTS          = 3
+	EMAIL_CHANGE_RATE_LIMIT_WINDOW     = time.Hour
+	EMAIL_CHANGE_RATE_LIMIT_KEY_PREFIX = "email_change_requests:"
+)
+
+// ChangeEmailHandler sends a confirmation link to the new address and a notice to the current one,
+// the email only changes once the link is confirmed (ConfirmEmailChangeHandler)
+func (h *Handler) ChangeEmailHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var changeEmailPayload ChangeEmailRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&changeEmailPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	newEmail := strings.ToLower(strings.TrimSpace(changeEmailPayload.NewEmail))
+
+	if newEmail == "" || strings.TrimSpace(changeEmailPayload.Password) == "" {
+		writeJSONError(w, "new email and password are required", http.StatusBadRequest)
+		return
+	}
+
+	if !isValidEmail(newEmail) {
+		writeJSONError(w, "invalid email", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(strings.TrimSpace(changeEmailPayload.Password))); err != nil {
+		writeJSONError(w, "invalid password", http.StatusBadRequest)
+		return
+	}
+
+	if newEmail == user.Email {
+		writeJSONError(w, "new email is the same as the current email", http.StatusBadRequest)
+		return
+	}
+
+	existingVerifiedUser, err := h.storage.GetVerifiedUserByEmail(newEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to get verified user by email: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if existingVerifiedUser != nil {
+		writeJSONError(w, "email is already in use", http.StatusBadRequest)
+		return
+	}
+
+	isAllowed, err := h.allowRateLimitedRequest(EMAIL_CHANGE_RATE_LIMIT_KEY_PREFIX+strconv.Itoa(userId), MAX_EMAIL_CHANGE_REQUESTS, EMAIL_CHANGE_RATE_LIMIT_WINDOW)
+	if err != nil {
+		log.Printf("failed to check email change rate limit: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isAllowed {
+		writeJSONError(w, "too many email change requests, please try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	plainTextToken, hashedTokenStr, err := generateToken(32)
+	if err != nil {
+		log.Printf("failed to generate token: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := h.storage.CreateEmailChange(user.Id, hashedTokenStr, newEmail, time.Now().Add(EMAIL_CHANGE_EXPIRATION)); err != nil {
+		log.Printf("failed to create email change: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	emailChangeMailData := EmailData{
+		EmailType:      EmailTypeEmailChange,
+		Subject:        "Confirm your new email address",
+		Email:          newEmail,
+		EmailChangeUrl: fmt.Sprintf("%s/confirm-email/%s", h.clientUrl, plainTextToken),
+	}
+
+	if err := h.pushEmailJob(emailChangeMailData); err != nil {
+		log.Printf("failed to push email job to redis queue: %v\n", err)
+		writeJSONError(w, "server failed to send confirmation mail, please contact support", http.StatusInternalServerError)
+		return
+	}
+
+	emailChangeNoticeMailData := EmailData{
+		EmailType: EmailTypeEmailChangeNotice,
+		Subject:   "Your email address is being changed",
+		Email:     user.Email,
+		NewEmail:  newEmail,
+	}
+
+	//	the change request is already made, a failed notice is only logged
+	if err := h.pushEmailJob(emailChangeNoticeMailData); err != nil {
+		log.Printf("failed to push email job to redis queue: %v\n", err)
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "a confirmation link will be sent to the new email shortly"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// ConfirmEmailChangeHandler the token from the confirmation link is enough (no login needed),
+// like activating an account
+func (h *Handler) ConfirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+
+	plainTextToken := chi.URLParam(r, "token")
+
+	user, err := h.storage.ConfirmEmailChange(hashToken(plainTextToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "invalid or expired email confirmation link", http.StatusBadRequest)
+			return
+		} else if errors.Is(err, storage.ErrEmailTaken) {
+			writeJSONError(w, "email is already in use", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to confirm email change: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success bool         `json:"success"`
+		Message string       `json:"message"`
+		User    storage.User `json:"user"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "email changed successfully", User: *user}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// blogETag strong ETag derived from the blog's version, it changes on every write to the blog
+func blogETag(blog *storage.Blog) string {
+	return fmt.Sprintf(`"blog-%d-v%d"`, blog.Id, blog.BlogVersion)
+}
+
+// isBlogIfMatchSatisfied a missing If-Match header (or *) always matches
+func isBlogIfMatchSatisfied(r *http.Request, blog *storage.Blog) bool {
+
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return true
+	}
+
+	currentETag := blogETag(blog)
+
+	for _, eTag := range strings.Split(ifMatch, ",") {
+		if strings.TrimSpace(eTag) == currentETag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeBlogPreconditionFailed responds with 412 and the blog's current version so that the client can merge
+func (h *Handler) writeBlogPreconditionFailed(w http.ResponseWriter, blogId int) {
+
+	currentBlog, err := h.storage.GetBlogWithMetaDataById(blogId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "blog does not exist", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to get blog by id: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success     bool                     `json:"success"`
+		Message     string                   `json:"message"`
+		BlogVersion int                      `json:"blog_version"`
+		Blog        storage.BlogWithMetaData `json:"blog"`
+	}
+
+	w.Header().Set("ETag", blogETag(&currentBlog.Blog))
+
+	if err := writeJSON(w, Response{Success: false, Message: "blog was modified by another request", BlogVersion: currentBlog.BlogVersion, Blog: *currentBlog}, http.StatusPreconditionFailed); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -8,8 +8,11 @@ import (
 	"github.com/google/uuid"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
 )
 
 const (
@@ -31,30 +34,48 @@ func (h *Handler) UploadImageFileHandler(w http.ResponseWriter, r *http.Request)
 	log.Println("Uploaded file size: ", fileHeader.Size)
 	log.Println("MIME header: ", fileHeader.Header)
 
+	imageUrl, err := h.uploadImageFile(file, fileHeader.Filename)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		Url     string `json:"url"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "uploaded file successfully", Url: imageUrl}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// uploadImageFile stores the file under ./uploads, uploads it to cloudinary (with retries) and returns its url
+func (h *Handler) uploadImageFile(file multipart.File, fileName string) (string, error) {
+
 	// ./uploads/filename.ext
-	uniqueFileName := fmt.Sprintf("%s-%s", fileHeader.Filename, uuid.New().String())
+	uniqueFileName := fmt.Sprintf("%s-%s", fileName, uuid.New().String())
 	uploadedFileDestination := fmt.Sprintf("./uploads/%s", uniqueFileName)
 	uploadedFile, err := os.Create(uploadedFileDestination)
 	if err != nil {
 		log.Printf("failed to create uploaded file destination %v\n", err)
-		writeJSONError(w, "internal server error", http.StatusInternalServerError)
-		return
+		return "", err
 	}
 
 	_, err = io.Copy(uploadedFile, file)
 	if err != nil {
 		log.Printf("failed to copy file contents to uploaded file destination: %v\n", err)
 		uploadedFile.Close()
-		writeJSONError(w, "internal server error", http.StatusInternalServerError)
-		return
+		return "", err
 	}
 	_ = uploadedFile.Close()
 
 	newUploadedFile, err := os.Open(uploadedFileDestination)
 	if err != nil {
 		log.Printf("failed to open uploaded file destination: %v\n", err)
-		writeJSONError(w, "internal server error", http.StatusInternalServerError)
-		return
+		return "", err
 	}
 
 	isUploadedFileToCloudinary := false
@@ -84,8 +105,7 @@ func (h *Handler) UploadImageFileHandler(w http.ResponseWriter, r *http.Request)
 	if !isUploadedFileToCloudinary {
 		log.Printf("failed to upload file to cloudinary: %v\n", uploadFileToCloudinaryErr)
 		_ = newUploadedFile.Close()
-		writeJSONError(w, "internal server error", http.StatusInternalServerError)
-		return
+		return "", uploadFileToCloudinaryErr
 	}
 
 	_ = newUploadedFile.Close()
@@ -93,16 +113,21 @@ func (h *Handler) UploadImageFileHandler(w http.ResponseWriter, r *http.Request)
 		log.Printf("failed to remove uploaded file from uploads: %v\n", err)
 	}
 
-	type Response struct {
-		Success bool   `json:"success"`
-		Message string `json:"message"`
-		Url     string `json:"url"`
-	}
+	return uploadResult.SecureURL, nil
+}
 
-	if err := writeJSON(w, Response{Success: true, Message: "uploaded file successfully", Url: uploadResult.SecureURL}, http.StatusOK); err != nil {
-		writeJSONError(w, "internal server error", http.StatusInternalServerError)
-		return
+// isUploadedImageUrl reports whether imageUrl points to an image uploaded to this app's cloudinary cloud
+func (h *Handler) isUploadedImageUrl(imageUrl string) bool {
+
+	parsedUrl, err := url.Parse(imageUrl)
+	if err != nil {
+		return false
 	}
+
+	cloudName := h.cloudinaryClient.Config.Cloud.CloudName
+
+	return parsedUrl.Scheme == "https" && parsedUrl.Host == "res.cloudinary.com" &&
+		strings.HasPrefix(parsedUrl.Path, fmt.Sprintf("/%s/image/upload/", cloudName))
 }
 
 //30/08/25
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/dhruv15803/go-blog-app/internal/oauth"
 	"github.com/dhruv15803/go-blog-app/internal/storage"
 	"github.com/redis/go-redis/v9"
 	"net/http"
@@ -12,14 +13,27 @@ type Handler struct {
 	redisClient      *redis.Client
 	cloudinaryClient *cloudinary.Cloudinary
 	clientUrl        string
+	// admins must enable 2fa before they can use admin routes
+	requireAdminTwoFactor bool
+	oauthProviders        map[string]oauth.Provider // keyed by provider name
+	// warn users by email when failed logins lock their account out
+	sendSuspiciousLoginEmails bool
+	clientIp                  ClientIpConfig
+	// count failed logins per ip address too, only meaningful once clientIp resolves real client addresses
+	throttleLoginsByIp bool
 }
 
-func NewHandler(storage *storage.Storage, redisClient *redis.Client, cloudinaryClient *cloudinary.Cloudinary, clientUrl string) *Handler {
+func NewHandler(storage *storage.Storage, redisClient *redis.Client, cloudinaryClient *cloudinary.Cloudinary, clientUrl string, requireAdminTwoFactor bool, oauthProviders map[string]oauth.Provider, sendSuspiciousLoginEmails bool, clientIp ClientIpConfig, throttleLoginsByIp bool) *Handler {
 	return &Handler{
-		storage:          storage,
-		redisClient:      redisClient,
-		cloudinaryClient: cloudinaryClient,
-		clientUrl:        clientUrl,
+		storage:                   storage,
+		redisClient:               redisClient,
+		cloudinaryClient:          cloudinaryClient,
+		clientUrl:                 clientUrl,
+		requireAdminTwoFactor:     requireAdminTwoFactor,
+		oauthProviders:            oauthProviders,
+		sendSuspiciousLoginEmails: sendSuspiciousLoginEmails,
+		clientIp:                  clientIp,
+		throttleLoginsByIp:        throttleLoginsByIp,
 	}
 }
 
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// loginThrottle failed login attempts (2fa codes, passwords) are counted per subject (a user id, an email address
+// or an ip address) in a sliding window, from delayAfter failures on every further attempt has to wait an
+// exponentially growing delay after the latest failure, maxFailures failures lock the subject out
+type loginThrottle struct {
+	failuresKeyPrefix string
+	lockoutKeyPrefix  string
+	window            time.Duration
+	delayAfter        int64
+	baseDelay         time.Duration
+	maxDelay          time.Duration
+	maxFailures       int64
+	lockout           time.Duration
+}
+
+const (
+	LOGIN_FAILURES_WINDOW = time.Minute * 15
+	LOGIN_LOCKOUT         = time.Minute * 15
+	LOGIN_BASE_DELAY      = time.Second
+	LOGIN_MAX_DELAY       = time.Second * 30
+	// per account (email address), the email address is used whether an account exists for it or not
+	MAX_ACCOUNT_LOGIN_FAILURES = 10
+	ACCOUNT_LOGIN_DELAY_AFTER  = 3
+	ACCOUNT_LOGIN_FAILURES_KEY = "login_failures:account:"
+	ACCOUNT_LOGIN_LOCKOUT_KEY  = "login_lockout:account:"
+	// per ip address, higher limits since many users can share an address
+	MAX_IP_LOGIN_FAILURES = 100
+	IP_LOGIN_DELAY_AFTER  = 20
+	IP_LOGIN_FAILURES_KEY = "login_failures:ip:"
+	IP_LOGIN_LOCKOUT_KEY  = "login_lockout:ip:"
+	// invalid 2fa codes per user across all of their challenges (and disabling 2fa, regenerating recovery
+	// codes), a new challenge per password login must not mean a new set of guesses
+	MAX_TWO_FACTOR_FAILURES = 10
+	TWO_FACTOR_FAILURES_KEY = "two_factor_failures:user:"
+	TWO_FACTOR_LOCKOUT_KEY  = "two_factor_lockout:user:"
+)
+
+var (
+	accountLoginThrottle = loginThrottle{
+		failuresKeyPrefix: ACCOUNT_LOGIN_FAILURES_KEY,
+		lockoutKeyPrefix:  ACCOUNT_LOGIN_LOCKOUT_KEY,
+		window:            LOGIN_FAILURES_WINDOW,
+		delayAfter:        ACCOUNT_LOGIN_DELAY_AFTER,
+		baseDelay:         LOGIN_BASE_DELAY,
+		maxDelay:          LOGIN_MAX_DELAY,
+		maxFailures:       MAX_ACCOUNT_LOGIN_FAILURES,
+		lockout:           LOGIN_LOCKOUT,
+	}
+	ipLoginThrottle = loginThrottle{
+		failuresKeyPrefix: IP_LOGIN_FAILURES_KEY,
+		lockoutKeyPrefix:  IP_LOGIN_LOCKOUT_KEY,
+		window:            LOGIN_FAILURES_WINDOW,
+		delayAfter:        IP_LOGIN_DELAY_AFTER,
+		baseDelay:         LOGIN_BASE_DELAY,
+		maxDelay:          LOGIN_MAX_DELAY,
+		maxFailures:       MAX_IP_LOGIN_FAILURES,
+		lockout:           LOGIN_LOCKOUT,
+	}
+	// no delays, MAX_TWO_FACTOR_FAILURES invalid codes lock the user's second factor out
+	twoFactorThrottle = loginThrottle{
+		failuresKeyPrefix: TWO_FACTOR_FAILURES_KEY,
+		lockoutKeyPrefix:  TWO_FACTOR_LOCKOUT_KEY,
+		window:            LOGIN_FAILURES_WINDOW,
+		delayAfter:        MAX_TWO_FACTOR_FAILURES,
+		baseDelay:         LOGIN_BASE_DELAY,
+		maxDelay:          LOGIN_MAX_DELAY,
+		maxFailures:       MAX_TWO_FACTOR_FAILURES,
+		lockout:           LOGIN_LOCKOUT,
+	}
+)
+
+// dummyPasswordHash compared against when no account exists for the email, so that unknown
+// emails take as long as wrong passwords
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// retryAfter how long the subject has to wait before its next attempt, 0 when it may try now
+func (t loginThrottle) retryAfter(redisClient *redis.Client, subject string) (time.Duration, error) {
+
+	ctx := context.Background()
+
+	lockoutTtl, err := redisClient.PTTL(ctx, t.lockoutKeyPrefix+subject).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// -2 when the key does not exist
+	if lockoutTtl > 0 {
+		return lockoutTtl, nil
+	}
+
+	now := time.Now()
+	failuresKey := t.failuresKeyPrefix + subject
+
+	var failuresCountCmd *redis.IntCmd
+	var latestFailureCmd *redis.ZSliceCmd
+
+	if _, err := redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, failuresKey, "-inf", strconv.FormatInt(now.Add(-t.window).UnixNano(), 10))
+		failuresCountCmd = pipe.ZCard(ctx, failuresKey)
+		latestFailureCmd = pipe.ZRevRangeWithScores(ctx, failuresKey, 0, 0)
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	delay := t.delay(failuresCountCmd.Val())
+	if delay == 0 || len(latestFailureCmd.Val()) == 0 {
+		return 0, nil
+	}
+
+	latestFailureAt := time.Unix(0, int64(latestFailureCmd.Val()[0].Score))
+
+	return max(latestFailureAt.Add(delay).Sub(now), 0), nil
+}
+
+// delay the wait after the latest failure with failuresCount failures in the window, 0 below delayAfter
+func (t loginThrottle) delay(failuresCount int64) time.Duration {
+
+	if failuresCount < t.delayAfter {
+		return 0
+	}
+
+	if exponent := failuresCount - t.delayAfter; exponent < 16 {
+		return min(t.baseDelay<<exponent, t.maxDelay)
+	}
+
+	return t.maxDelay
+}
+
+func (t loginThrottle) isLockoutReached(failuresCount int64) bool {
+	return failuresCount >= t.maxFailures
+}
+
+// recordFailure adds a failure to the subject's window, returns whether this failure locked the subject out
+// (the failures are forgotten then, the lockout takes over)
+func (t loginThrottle) recordFailure(redisClient *redis.Client, subject string) (bool, error) {
+
+	ctx := context.Background()
+	now := time.Now()
+	failuresKey := t.failuresKeyPrefix + subject
+
+	var failuresCountCmd *redis.IntCmd
+
+	if _, err := redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, failuresKey, "-inf", strconv.FormatInt(now.Add(-t.window).UnixNano(), 10))
+		// members have to be unique for concurrent failures at the same instant to both count
+		pipe.ZAdd(ctx, failuresKey, redis.Z{Score: float64(now.UnixNano()), Member: fmt.Sprintf("%d:%d", now.UnixNano(), rand.Int64())})
+		failuresCountCmd = pipe.ZCard(ctx, failuresKey)
+		pipe.Expire(ctx, failuresKey, t.window)
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	if !t.isLockoutReached(failuresCountCmd.Val()) {
+		return false, nil
+	}
+
+	isLockedOut, err := redisClient.SetNX(ctx, t.lockoutKeyPrefix+subject, now.Unix(), t.lockout).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if err := redisClient.Del(ctx, failuresKey).Err(); err != nil {
+		return false, err
+	}
+
+	return isLockedOut, nil
+}
+
+func (t loginThrottle) clearFailures(redisClient *redis.Client, subject string) error {
+	return redisClient.Del(context.Background(), t.failuresKeyPrefix+subject).Err()
+}
+
+// writeRetryAfterError 429 with a Retry-After header in whole seconds
+func writeRetryAfterError(w http.ResponseWriter, message string, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	writeJSONError(w, message, http.StatusTooManyRequests)
+}
+
+// loginRetryAfter the longer wait of the account (email) and the ip address (when logins are throttled by ip)
+func (h *Handler) loginRetryAfter(email string, ipAddress string) (time.Duration, error) {
+
+	accountRetryAfter, err := accountLoginThrottle.retryAfter(h.redisClient, email)
+	if err != nil {
+		return 0, err
+	}
+
+	if !h.throttleLoginsByIp {
+		return accountRetryAfter, nil
+	}
+
+	ipRetryAfter, err := ipLoginThrottle.retryAfter(h.redisClient, ipAddress)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(accountRetryAfter, ipRetryAfter), nil
+}
+
+// recordLoginFailure counts a failed login for the email (and the ip address), user is nil when no verified
+// account exists for the email. when the failure locks an existing account out its owner is warned by
+// email (if suspicious login emails are enabled)
+func (h *Handler) recordLoginFailure(email string, ipAddress string, user *storage.User) error {
+
+	isAccountLockedOut, err := accountLoginThrottle.recordFailure(h.redisClient, email)
+	if err != nil {
+		return err
+	}
+
+	if h.throttleLoginsByIp {
+		if _, err := ipLoginThrottle.recordFailure(h.redisClient, ipAddress); err != nil {
+			return err
+		}
+	}
+
+	if !isAccountLockedOut || user == nil || !h.sendSuspiciousLoginEmails {
+		return nil
+	}
+
+	suspiciousLoginMailData := EmailData{
+		EmailType:      EmailTypeSuspiciousLogin,
+		Subject:        "Suspicious sign-in attempts on your account",
+		Email:          user.Email,
+		IpAddress:      ipAddress,
+		FailedAttempts: MAX_ACCOUNT_LOGIN_FAILURES,
+	}
+
+	//	the failed login has been recorded, a lost warning email should not turn it into a server error
+	if err := h.pushEmailJob(suspiciousLoginMailData); err != nil {
+		log.Printf("failed to push email job to redis queue: %v\n", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginThrottleDelay(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		throttle      loginThrottle
+		failuresCount int64
+		delay         time.Duration
+	}{
+		{"account, no failures", accountLoginThrottle, 0, 0},
+		{"account, below delay threshold", accountLoginThrottle, ACCOUNT_LOGIN_DELAY_AFTER - 1, 0},
+		{"account, at delay threshold", accountLoginThrottle, ACCOUNT_LOGIN_DELAY_AFTER, time.Second},
+		{"account, one past delay threshold", accountLoginThrottle, ACCOUNT_LOGIN_DELAY_AFTER + 1, 2 * time.Second},
+		{"account, two past delay threshold", accountLoginThrottle, ACCOUNT_LOGIN_DELAY_AFTER + 2, 4 * time.Second},
+		{"account, four past delay threshold", accountLoginThrottle, ACCOUNT_LOGIN_DELAY_AFTER + 4, 16 * time.Second},
+		{"account, capped", accountLoginThrottle, ACCOUNT_LOGIN_DELAY_AFTER + 5, LOGIN_MAX_DELAY},
+		{"account, shift overflow", accountLoginThrottle, ACCOUNT_LOGIN_DELAY_AFTER + 100, LOGIN_MAX_DELAY},
+		{"ip, below delay threshold", ipLoginThrottle, IP_LOGIN_DELAY_AFTER - 1, 0},
+		{"ip, at delay threshold", ipLoginThrottle, IP_LOGIN_DELAY_AFTER, time.Second},
+		{"ip, capped", ipLoginThrottle, MAX_IP_LOGIN_FAILURES - 1, LOGIN_MAX_DELAY},
+		{"two factor, below lockout", twoFactorThrottle, MAX_TWO_FACTOR_FAILURES - 1, 0},
+	}
+
+	for _, test := range tests {
+		if delay := test.throttle.delay(test.failuresCount); delay != test.delay {
+			t.Errorf("%s: delay(%d) = %v, want %v", test.name, test.failuresCount, delay, test.delay)
+		}
+	}
+}
+
+func TestLoginThrottleLockout(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		throttle         loginThrottle
+		failuresCount    int64
+		isLockoutReached bool
+	}{
+		{"account, below threshold", accountLoginThrottle, MAX_ACCOUNT_LOGIN_FAILURES - 1, false},
+		{"account, at threshold", accountLoginThrottle, MAX_ACCOUNT_LOGIN_FAILURES, true},
+		{"ip, below threshold", ipLoginThrottle, MAX_IP_LOGIN_FAILURES - 1, false},
+		{"ip, at threshold", ipLoginThrottle, MAX_IP_LOGIN_FAILURES, true},
+		{"two factor, below threshold", twoFactorThrottle, MAX_TWO_FACTOR_FAILURES - 1, false},
+		{"two factor, at threshold", twoFactorThrottle, MAX_TWO_FACTOR_FAILURES, true},
+	}
+
+	for _, test := range tests {
+		if isLockoutReached := test.throttle.isLockoutReached(test.failuresCount); isLockoutReached != test.isLockoutReached {
+			t.Errorf("%s: isLockoutReached(%d) = %v, want %v", test.name, test.failuresCount, isLockoutReached, test.isLockoutReached)
+		}
+	}
+}
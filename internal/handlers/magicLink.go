@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+const (
+	MAGIC_LINK_EXPIRATION = time.Minute * 10
+	// sign-in link requests per email address within the rate limit window
+	MAX_MAGIC_LINK_REQUESTS          = 3
+	MAGIC_LINK_RATE_LIMIT_WINDOW     = time.Minute * 15
+	MAGIC_LINK_RATE_LIMIT_KEY_PREFIX = "magic_link_requests:"
+)
+
+// MagicLinkHandler emails a single use sign-in link to a verified user
+func (h *Handler) MagicLinkHandler(w http.ResponseWriter, r *http.Request) {
+
+	var magicLinkPayload MagicLinkRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&magicLinkPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userEmail := strings.ToLower(strings.TrimSpace(magicLinkPayload.Email))
+
+	if userEmail == "" {
+		writeJSONError(w, "email is required", http.StatusBadRequest)
+		return
+	}
+
+	if !isValidEmail(userEmail) {
+		writeJSONError(w, "invalid email", http.StatusBadRequest)
+		return
+	}
+
+	// limited per address whether an account exists for it or not
+	isAllowed, err := h.allowRateLimitedRequest(MAGIC_LINK_RATE_LIMIT_KEY_PREFIX+userEmail, MAX_MAGIC_LINK_REQUESTS, MAGIC_LINK_RATE_LIMIT_WINDOW)
+	if err != nil {
+		log.Printf("failed to check magic link rate limit: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isAllowed {
+		writeJSONError(w, "too many sign-in link requests, please try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	// the response is the same whether an account exists for the email or not (no user enumeration)
+	response := Response{Success: true, Message: "if an account exists for this email, a sign-in link will be sent shortly"}
+
+	user, err := h.storage.GetVerifiedUserByEmail(userEmail)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if err := writeJSON(w, response, http.StatusOK); err != nil {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			}
+			return
+		} else {
+			log.Printf("failed to get verified user by email: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	plainTextToken, hashedTokenStr, err := generateToken(32)
+	if err != nil {
+		log.Printf("failed to generate token: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	//	from here on failures only get logged, an error response would tell that the account exists
+	if _, err := h.storage.CreateMagicLink(user.Id, hashedTokenStr, time.Now().Add(MAGIC_LINK_EXPIRATION)); err != nil {
+		log.Printf("failed to create magic link: %v\n", err)
+		if err := writeJSON(w, response, http.StatusOK); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	magicLinkMailData := EmailData{
+		EmailType:    EmailTypeMagicLink,
+		Subject:      "Your sign-in link",
+		Email:        user.Email,
+		MagicLinkUrl: fmt.Sprintf("%s/magic-link/%s", h.clientUrl, plainTextToken),
+	}
+
+	if err := h.pushEmailJob(magicLinkMailData); err != nil {
+		log.Printf("failed to push email job to redis queue: %v\n", err)
+	}
+
+	if err := writeJSON(w, response, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// ConsumeMagicLinkHandler logs the user in the same way LoginUserHandler does (2fa included), the link is single use
+func (h *Handler) ConsumeMagicLinkHandler(w http.ResponseWriter, r *http.Request) {
+
+	plainTextToken := chi.URLParam(r, "token")
+
+	user, err := h.storage.ConsumeMagicLink(hashToken(plainTextToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "invalid or expired sign-in link", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to consume magic link: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.completeLogin(w, r, user)
+}
@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/dhruv15803/go-blog-app/utils"
+	"log"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// endpoints under /me act on the authenticated user's own data
+
+const (
+	MAX_NAME_LENGTH               = 50
+	MAX_PROFILE_IMG_UPLOAD_MEMORY = 10 << 20
+	PROFILE_IMG_FILE_KEY          = "profile_img_file"
+)
+
+// UpdateMeRequest fields left out keep their current value, an empty string clears the field
+type UpdateMeRequest struct {
+	Username   *string `json:"username"`
+	Name       *string `json:"name"`
+	ProfileImg *string `json:"profile_img"` // url returned by POST /file/upload
+}
+
+// UpdateMeHandler edits the authenticated user's profile, the body is either JSON (UpdateMeRequest)
+// or multipart/form-data with optional username and name fields and a profile_img_file avatar upload
+func (h *Handler) UpdateMeHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var updateMePayload UpdateMeRequest
+	var profileImgFile multipart.File
+	var profileImgFileHeader *multipart.FileHeader
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+
+		if err := r.ParseMultipartForm(MAX_PROFILE_IMG_UPLOAD_MEMORY); err != nil {
+			writeJSONError(w, "invalid multipart/form-data request body", http.StatusBadRequest)
+			return
+		}
+
+		if usernames, ok := r.MultipartForm.Value["username"]; ok && len(usernames) > 0 {
+			updateMePayload.Username = &usernames[0]
+		}
+		if names, ok := r.MultipartForm.Value["name"]; ok && len(names) > 0 {
+			updateMePayload.Name = &names[0]
+		}
+
+		profileImgFile, profileImgFileHeader, err = r.FormFile(PROFILE_IMG_FILE_KEY)
+		if err != nil && !errors.Is(err, http.ErrMissingFile) {
+			writeJSONError(w, fmt.Sprintf("failed to extract file from multipart/form-data with key %s", PROFILE_IMG_FILE_KEY), http.StatusBadRequest)
+			return
+		}
+		if profileImgFile != nil {
+			defer profileImgFile.Close()
+		}
+	} else {
+		if err := json.NewDecoder(r.Body).Decode(&updateMePayload); err != nil {
+			writeJSONError(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	username := user.Username
+	name := user.Name
+	profileImg := user.ProfileImg
+
+	if updateMePayload.Username != nil {
+		newUsername := strings.ToLower(strings.TrimSpace(*updateMePayload.Username))
+
+		if newUsername == "" {
+			username = nil
+		} else {
+			if !utils.IsValidUsername(newUsername) {
+				writeJSONError(w, "invalid username, use 3-30 lowercase letters, numbers, '_' or '.' with atleast one letter", http.StatusBadRequest)
+				return
+			}
+
+			existingUser, err := h.storage.GetUserByUsername(newUsername)
+			if err == nil && existingUser.Id != user.Id {
+				writeJSONError(w, "username already taken", http.StatusBadRequest)
+				return
+			}
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			username = &newUsername
+		}
+	}
+
+	if updateMePayload.Name != nil {
+		newName := strings.TrimSpace(*updateMePayload.Name)
+
+		if newName == "" {
+			name = nil
+		} else {
+			if utf8.RuneCountInString(newName) > MAX_NAME_LENGTH {
+				writeJSONError(w, fmt.Sprintf("name can have max %v characters", MAX_NAME_LENGTH), http.StatusBadRequest)
+				return
+			}
+
+			name = &newName
+		}
+	}
+
+	if updateMePayload.ProfileImg != nil {
+		newProfileImg := strings.TrimSpace(*updateMePayload.ProfileImg)
+
+		if newProfileImg == "" {
+			profileImg = nil
+		} else {
+			if !h.isUploadedImageUrl(newProfileImg) {
+				writeJSONError(w, "invalid profile_img, upload the image with /file/upload first", http.StatusBadRequest)
+				return
+			}
+
+			profileImg = &newProfileImg
+		}
+	}
+
+	//	upload last, so that an invalid request never leaves an orphan image behind
+	if profileImgFile != nil {
+		profileImgUrl, err := h.uploadImageFile(profileImgFile, profileImgFileHeader.Filename)
+		if err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		profileImg = &profileImgUrl
+	}
+
+	updatedUser, err := h.storage.UpdateUserProfile(user.Id, username, name, profileImg)
+	if err != nil {
+		if errors.Is(err, storage.ErrUsernameTaken) {
+			writeJSONError(w, "username already taken", http.StatusBadRequest)
+			return
+		}
+		log.Printf("failed to update user profile: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool         `json:"success"`
+		Message string       `json:"message"`
+		User    storage.User `json:"user"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "profile updated successfully", User: *updatedUser}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// bookmarks of the authenticated user, ?folder_id= lists a single bookmark folder
+func (h *Handler) GetMyBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var bookmarkFolderId *int
+
+	if r.URL.Query().Get("folder_id") != "" {
+		folderId, err := strconv.Atoi(r.URL.Query().Get("folder_id"))
+		if err != nil {
+			writeJSONError(w, "invalid query param folder_id", http.StatusBadRequest)
+			return
+		}
+
+		bookmarkFolder, err := h.storage.GetBookmarkFolderById(folderId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSONError(w, "bookmark folder does not exist", http.StatusBadRequest)
+				return
+			} else {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if bookmarkFolder.UserId != user.Id {
+			writeJSONError(w, "unauthorized to view bookmark folder", http.StatusUnauthorized)
+			return
+		}
+
+		bookmarkFolderId = &bookmarkFolder.Id
+	}
+
+	var page int
+	var limit int
+
+	if r.URL.Query().Get("page") == "" {
+		page = 1
+	} else {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+			return
+		}
+	}
+	if r.URL.Query().Get("limit") == "" {
+		limit = 10
+	} else {
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil {
+			writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	skip := page*limit - limit
+
+	blogs, err := h.storage.GetUserBookmarkedBlogs(user.Id, bookmarkFolderId, skip, limit)
+	if err != nil {
+		log.Printf("failed to get bookmarked blogs: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalBlogsCount, err := h.storage.GetUserBookmarkedBlogsCount(user.Id, bookmarkFolderId)
+	if err != nil {
+		log.Printf("failed to get bookmarked blogs count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalBlogsCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool                     `json:"success"`
+		Blogs     []storage.BookmarkedBlog `json:"blogs"`
+		NoOfPages int                      `json:"no_of_pages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Blogs: blogs, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) GetMyLikesHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var page int
+	var limit int
+
+	if r.URL.Query().Get("page") == "" {
+		page = 1
+	} else {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+			return
+		}
+	}
+	if r.URL.Query().Get("limit") == "" {
+		limit = 10
+	} else {
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil {
+			writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	skip := page*limit - limit
+
+	blogs, err := h.storage.GetUserLikedBlogs(user.Id, skip, limit)
+	if err != nil {
+		log.Printf("failed to get liked blogs: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalBlogsCount, err := h.storage.GetUserLikedBlogsCount(user.Id)
+	if err != nil {
+		log.Printf("failed to get liked blogs count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalBlogsCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool                `json:"success"`
+		Blogs     []storage.LikedBlog `json:"blogs"`
+		NoOfPages int                 `json:"no_of_pages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Blogs: blogs, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// ModerateDeleteBlogCommentHandler requires the comment:moderate permission, deletes any user's
+// comment (and the replies under it), authors delete their own comments with DeleteBlogCommentHandler
+func (h *Handler) ModerateDeleteBlogCommentHandler(w http.ResponseWriter, r *http.Request) {
+
+	blogCommentId, err := strconv.ParseInt(chi.URLParam(r, "blogCommentId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param blogCommentId", http.StatusBadRequest)
+		return
+	}
+
+	blogComment, err := h.storage.GetBlogCommentById(int(blogCommentId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "blog comment not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := h.storage.DeleteBlogCommentById(blogComment.Id); err != nil {
+		log.Printf("failed to delete blog comment: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "blog comment deleted"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
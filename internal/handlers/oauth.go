@@ -0,0 +1,439 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dhruv15803/go-blog-app/internal/oauth"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	OAUTH_STATE_KEY_PREFIX = "oauth_state:"
+	OAUTH_STATE_EXPIRATION = time.Minute * 10
+	OAUTH_STATE_COOKIE     = "oauth_state"
+	OAUTH_COOKIE_PATH      = "/api/auth/oauth"
+)
+
+// oauthFlowState kept in redis (keyed by the state param) between the redirect to the provider and the callback
+type oauthFlowState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	LinkUserId   int    `json:"link_user_id"` // set when an authenticated user links another identity
+}
+
+func (h *Handler) GetOAuthProvidersHandler(w http.ResponseWriter, r *http.Request) {
+
+	providers := make([]string, 0, len(h.oauthProviders))
+	for providerName := range h.oauthProviders {
+		providers = append(providers, providerName)
+	}
+	slices.Sort(providers)
+
+	type Response struct {
+		Success   bool     `json:"success"`
+		Providers []string `json:"providers"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Providers: providers}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// OAuthLoginHandler redirects the browser to the provider's authorization page
+func (h *Handler) OAuthLoginHandler(w http.ResponseWriter, r *http.Request) {
+
+	provider, ok := h.oauthProviders[chi.URLParam(r, "provider")]
+	if !ok {
+		writeJSONError(w, "unknown login provider", http.StatusNotFound)
+		return
+	}
+
+	authorizationUrl, err := h.startOAuthFlow(w, r, provider, 0)
+	if err != nil {
+		log.Printf("failed to start oauth flow: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, authorizationUrl, http.StatusFound)
+}
+
+// LinkIdentityHandler starts the same flow for an authenticated user, the callback links the provider
+// account to this user instead of logging in. responds with the url to navigate to
+func (h *Handler) LinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	provider, ok := h.oauthProviders[chi.URLParam(r, "provider")]
+	if !ok {
+		writeJSONError(w, "unknown login provider", http.StatusNotFound)
+		return
+	}
+
+	authorizationUrl, err := h.startOAuthFlow(w, r, provider, userId)
+	if err != nil {
+		log.Printf("failed to start oauth flow: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success          bool   `json:"success"`
+		AuthorizationUrl string `json:"authorization_url"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, AuthorizationUrl: authorizationUrl}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// OAuthCallbackHandler the provider redirects back here, the browser is then redirected to
+// CLIENT_URL/oauth/callback with the outcome as query params (error, linked, two_factor_required + challenge_token)
+func (h *Handler) OAuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
+
+	providerName := chi.URLParam(r, "provider")
+
+	provider, ok := h.oauthProviders[providerName]
+	if !ok {
+		h.redirectOAuthResult(w, r, url.Values{"error": {"unknown_provider"}})
+		return
+	}
+
+	state := r.URL.Query().Get("state")
+
+	// the state has to match the cookie set for this browser when the flow started (login csrf)
+	stateCookie, err := r.Cookie(OAUTH_STATE_COOKIE)
+	clearOAuthStateCookie(w)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(stateCookie.Value), []byte(state)) != 1 {
+		h.redirectOAuthResult(w, r, url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	flowStateJson, err := h.redisClient.GetDel(context.Background(), OAUTH_STATE_KEY_PREFIX+state).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			h.redirectOAuthResult(w, r, url.Values{"error": {"invalid_state"}})
+			return
+		}
+		log.Printf("failed to get oauth state from redis: %v\n", err)
+		h.redirectOAuthResult(w, r, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	var flowState oauthFlowState
+	if err := json.Unmarshal([]byte(flowStateJson), &flowState); err != nil || flowState.Provider != providerName {
+		h.redirectOAuthResult(w, r, url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	// the user denied access or the provider failed
+	if providerError := r.URL.Query().Get("error"); providerError != "" {
+		h.redirectOAuthResult(w, r, url.Values{"error": {providerError}})
+		return
+	}
+
+	identity, err := provider.Exchange(r.Context(), r.URL.Query().Get("code"), flowState.CodeVerifier, flowState.Nonce)
+	if err != nil {
+		log.Printf("failed to exchange %s authorization code: %v\n", providerName, err)
+		h.redirectOAuthResult(w, r, url.Values{"error": {"provider_error"}})
+		return
+	}
+
+	if flowState.LinkUserId != 0 {
+		h.linkOAuthIdentity(w, r, flowState.LinkUserId, providerName, identity)
+		return
+	}
+
+	user, err := h.getOrCreateOAuthUser(providerName, identity)
+	if err != nil {
+		if errors.Is(err, errOAuthEmailNotVerified) {
+			h.redirectOAuthResult(w, r, url.Values{"error": {"email_not_verified"}})
+			return
+		}
+		log.Printf("failed to get or create oauth user: %v\n", err)
+		h.redirectOAuthResult(w, r, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	userSuspension, err := h.getActiveUserSuspension(user.Id)
+	if err != nil {
+		log.Printf("failed to get user suspension: %v\n", err)
+		h.redirectOAuthResult(w, r, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	if userSuspension != nil {
+		h.redirectOAuthResult(w, r, url.Values{"error": {"account_suspended"}})
+		return
+	}
+
+	isTwoFactorEnabled, err := h.storage.IsUserTwoFactorEnabled(user.Id)
+	if err != nil {
+		log.Printf("failed to check user two factor: %v\n", err)
+		h.redirectOAuthResult(w, r, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	// a provider login does not skip 2fa, the client finishes it with /auth/login/2fa
+	if isTwoFactorEnabled {
+		challengeToken, err := h.startTwoFactorChallenge(user.Id)
+		if err != nil {
+			log.Printf("failed to start two factor challenge: %v\n", err)
+			h.redirectOAuthResult(w, r, url.Values{"error": {"server_error"}})
+			return
+		}
+
+		h.redirectOAuthResult(w, r, url.Values{"two_factor_required": {"true"}, "challenge_token": {challengeToken}})
+		return
+	}
+
+	//	a browser redirect, the tokens are always set as cookies
+	if _, err := h.startUserSession(w, r, user.Id); err != nil {
+		log.Printf("failed to start user session: %v\n", err)
+		h.redirectOAuthResult(w, r, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	h.redirectOAuthResult(w, r, url.Values{})
+}
+
+func (h *Handler) GetMyIdentitiesHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userIdentities, err := h.storage.GetUserIdentities(userId)
+	if err != nil {
+		log.Printf("failed to get user identities: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success    bool                   `json:"success"`
+		Identities []storage.UserIdentity `json:"identities"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Identities: userIdentities}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) UnlinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	identityId, err := strconv.ParseInt(chi.URLParam(r, "identityId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param identityId", http.StatusBadRequest)
+		return
+	}
+
+	userIdentity, err := h.storage.GetUserIdentityById(int(identityId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "identity does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// identities of other users are reported as not existing
+	if userIdentity.UserId != userId {
+		writeJSONError(w, "identity does not exist", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.DeleteUserIdentity(userIdentity.Id); err != nil {
+		log.Printf("failed to delete user identity: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "identity unlinked successfully"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// startOAuthFlow stores state, nonce and the pkce verifier and returns the provider's authorization url
+func (h *Handler) startOAuthFlow(w http.ResponseWriter, r *http.Request, provider oauth.Provider, linkUserId int) (string, error) {
+
+	state, err := oauth.RandomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oauth.RandomString()
+	if err != nil {
+		return "", err
+	}
+	codeVerifier, err := oauth.RandomString()
+	if err != nil {
+		return "", err
+	}
+
+	authorizationUrl, err := provider.AuthCodeURL(r.Context(), state, nonce, oauth.PKCEChallenge(codeVerifier))
+	if err != nil {
+		return "", err
+	}
+
+	flowStateJson, err := json.Marshal(oauthFlowState{
+		Provider:     provider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		LinkUserId:   linkUserId,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := h.redisClient.Set(context.Background(), OAUTH_STATE_KEY_PREFIX+state, string(flowStateJson), OAUTH_STATE_EXPIRATION).Err(); err != nil {
+		return "", err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     OAUTH_STATE_COOKIE,
+		Value:    state,
+		HttpOnly: true,
+		Path:     OAUTH_COOKIE_PATH,
+		Secure:   os.Getenv("GO_ENV") == "production",
+		MaxAge:   int(OAUTH_STATE_EXPIRATION.Seconds()),
+		SameSite: authCookieSameSite(),
+	})
+
+	return authorizationUrl, nil
+}
+
+// errOAuthEmailNotVerified a new provider identity can only be matched / signed up by an email the provider verified
+var errOAuthEmailNotVerified = errors.New("provider email not verified")
+
+// getOrCreateOAuthUser the user of an already linked identity, else the verified user with the provider's
+// verified email (the identity gets linked), else a new verified user
+func (h *Handler) getOrCreateOAuthUser(providerName string, identity *oauth.Identity) (*storage.User, error) {
+
+	userIdentity, err := h.storage.GetUserIdentity(providerName, identity.Subject)
+	if err == nil {
+		if err := h.storage.TouchUserIdentity(userIdentity.Id); err != nil {
+			return nil, err
+		}
+		return h.storage.GetUserById(userIdentity.UserId)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified || !isValidEmail(identity.Email) {
+		return nil, errOAuthEmailNotVerified
+	}
+
+	user, err := h.storage.GetVerifiedUserByEmail(identity.Email)
+	if err == nil {
+		if _, err := h.storage.CreateUserIdentity(user.Id, providerName, identity.Subject, identity.Email); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	randomPassword, err := oauth.RandomString()
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(randomPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	var name *string
+	if identity.Name != "" && utf8.RuneCountInString(identity.Name) <= MAX_NAME_LENGTH {
+		name = &identity.Name
+	}
+
+	// the provider's picture is not copied, profile images are always our own uploads
+	return h.storage.CreateUserWithIdentity(identity.Email, string(hashedPassword), name, nil, providerName, identity.Subject)
+}
+
+func (h *Handler) linkOAuthIdentity(w http.ResponseWriter, r *http.Request, userId int, providerName string, identity *oauth.Identity) {
+
+	userIdentity, err := h.storage.GetUserIdentity(providerName, identity.Subject)
+	if err == nil {
+		if userIdentity.UserId != userId {
+			h.redirectOAuthResult(w, r, url.Values{"error": {"identity_linked_to_another_account"}})
+			return
+		}
+		h.redirectOAuthResult(w, r, url.Values{"linked": {providerName}})
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to get user identity: %v\n", err)
+		h.redirectOAuthResult(w, r, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	if _, err := h.storage.CreateUserIdentity(userId, providerName, identity.Subject, identity.Email); err != nil {
+		log.Printf("failed to create user identity: %v\n", err)
+		h.redirectOAuthResult(w, r, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	h.redirectOAuthResult(w, r, url.Values{"linked": {providerName}})
+}
+
+func (h *Handler) redirectOAuthResult(w http.ResponseWriter, r *http.Request, params url.Values) {
+
+	redirectUrl := fmt.Sprintf("%s/oauth/callback", h.clientUrl)
+	if len(params) > 0 {
+		redirectUrl += "?" + params.Encode()
+	}
+
+	http.Redirect(w, r, redirectUrl, http.StatusFound)
+}
+
+func clearOAuthStateCookie(w http.ResponseWriter) {
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     OAUTH_STATE_COOKIE,
+		Value:    "",
+		HttpOnly: true,
+		Path:     OAUTH_COOKIE_PATH,
+		Secure:   os.Getenv("GO_ENV") == "production",
+		MaxAge:   -1,
+		SameSite: authCookieSameSite(),
+	})
+}
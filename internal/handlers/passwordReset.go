@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+const (
+	PASSWORD_RESET_EXPIRATION = time.Minute * 15
+)
+
+func (h *Handler) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+
+	var forgotPasswordPayload ForgotPasswordRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&forgotPasswordPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userEmail := strings.ToLower(strings.TrimSpace(forgotPasswordPayload.Email))
+
+	if userEmail == "" {
+		writeJSONError(w, "email is required", http.StatusBadRequest)
+		return
+	}
+
+	if !isValidEmail(userEmail) {
+		writeJSONError(w, "invalid email", http.StatusBadRequest)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	// the response is the same whether an account exists for the email or not (no user enumeration)
+	response := Response{Success: true, Message: "if an account exists for this email, a password reset mail will be sent shortly"}
+
+	user, err := h.storage.GetVerifiedUserByEmail(userEmail)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if err := writeJSON(w, response, http.StatusOK); err != nil {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			}
+			return
+		} else {
+			log.Printf("failed to get verified user by email: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	//	a failure only gets logged, an error response here would tell that the account exists
+	if err := h.sendPasswordResetEmail(user); err != nil {
+		log.Printf("failed to send password reset email: %v\n", err)
+	}
+
+	if err := writeJSON(w, response, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// sendPasswordResetEmail creates a password reset for the user and queues the mail with its link,
+// shared by ForgotPasswordHandler and admins triggering a reset
+func (h *Handler) sendPasswordResetEmail(user *storage.User) error {
+
+	plainTextToken, hashedTokenStr, err := generateToken(32)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.storage.CreatePasswordReset(user.Id, hashedTokenStr, time.Now().Add(PASSWORD_RESET_EXPIRATION)); err != nil {
+		return err
+	}
+
+	passwordResetMailData := EmailData{
+		EmailType:        EmailTypePasswordReset,
+		Subject:          "Reset your password",
+		Email:            user.Email,
+		ResetPasswordUrl: fmt.Sprintf("%s/reset-password/%s", h.clientUrl, plainTextToken),
+	}
+
+	return h.pushEmailJob(passwordResetMailData)
+}
+
+func (h *Handler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+
+	plainTextToken := chi.URLParam(r, "token")
+
+	hashedToken := sha256.Sum256([]byte(plainTextToken))
+	hashedTokenStr := hex.EncodeToString(hashedToken[:])
+
+	var resetPasswordPayload ResetPasswordRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&resetPasswordPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	newPlainTextPassword := strings.TrimSpace(resetPasswordPayload.Password)
+
+	if newPlainTextPassword == "" {
+		writeJSONError(w, "password is required", http.StatusBadRequest)
+		return
+	}
+
+	if !isPasswordStrong(newPlainTextPassword) {
+		writeJSONError(w, "weak password", http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPlainTextPassword), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("failed to hash password: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = h.storage.ResetUserPassword(hashedTokenStr, string(hashedPassword))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "invalid or expired password reset token", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to reset user password: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "password reset successfully, please login with your new password"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
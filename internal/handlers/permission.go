@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"log"
+	"net/http"
+	"slices"
+)
+
+// Permission something a role may do, routes in api.go declare the permission they need with RequirePermission
+type Permission string
+
+const (
+	PermissionTopicManage     Permission = "topic:manage"
+	PermissionCommentModerate Permission = "comment:moderate"
+	PermissionBlogFeature     Permission = "blog:feature"
+	PermissionUserBan         Permission = "user:ban"
+	PermissionUserManage      Permission = "user:manage"
+)
+
+// rolePermissions the permission matrix, a role that is not listed (user) has no permissions
+var rolePermissions = map[storage.UserRole][]Permission{
+	storage.RoleAdmin: {
+		PermissionTopicManage,
+		PermissionCommentModerate,
+		PermissionBlogFeature,
+		PermissionUserBan,
+		PermissionUserManage,
+	},
+	storage.RoleModerator: {
+		PermissionCommentModerate,
+		PermissionUserBan,
+	},
+	storage.RoleEditor: {
+		PermissionTopicManage,
+		PermissionBlogFeature,
+	},
+}
+
+func RoleHasPermission(role storage.UserRole, permission Permission) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+// RolePermissions the permissions of the role (nil for user)
+func RolePermissions(role storage.UserRole) []Permission {
+	return rolePermissions[role]
+}
+
+// isStaffRole roles with at least one permission, REQUIRE_ADMIN_2FA applies to all of them
+func isStaffRole(role storage.UserRole) bool {
+	return len(rolePermissions[role]) > 0
+}
+
+// RequirePermission runs after AuthMiddleware, the authenticated user's role must grant the permission
+func (h *Handler) RequirePermission(permission Permission) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			userId, ok := r.Context().Value(AuthUserId).(int)
+			if !ok {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			//	staff routes are never available to personal access tokens
+			if tokenScopes, _ := r.Context().Value(AuthTokenScopes).([]string); tokenScopes != nil {
+				writeJSONError(w, "staff routes require a login session", http.StatusForbidden)
+				return
+			}
+
+			user, err := h.storage.GetUserById(userId)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					writeJSONError(w, "user does not exist", http.StatusBadRequest)
+					return
+				} else {
+					writeJSONError(w, "internal server error", http.StatusInternalServerError)
+					return
+				}
+			}
+
+			if !RoleHasPermission(user.Role, permission) {
+				writeJSONError(w, "missing permission "+string(permission), http.StatusForbidden)
+				return
+			}
+
+			isTwoFactorSetupRequired, err := h.isTwoFactorSetupRequired(user)
+			if err != nil {
+				log.Printf("failed to check two factor setup: %v\n", err)
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			if isTwoFactorSetupRequired {
+				writeJSONError(w, "two factor authentication must be enabled for staff accounts", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// personal access token scopes
+const (
+	TokenScopeRead          = "read"
+	TokenScopeBlogsWrite    = "blogs:write"
+	TokenScopeCommentsWrite = "comments:write"
+)
+
+const (
+	PERSONAL_ACCESS_TOKEN_PREFIX          = "gba_pat_"
+	MAX_PERSONAL_ACCESS_TOKENS            = 20
+	MAX_PERSONAL_ACCESS_TOKEN_NAME_LENGTH = 50
+	DEFAULT_PERSONAL_ACCESS_TOKEN_DAYS    = 30
+	MAX_PERSONAL_ACCESS_TOKEN_DAYS        = 365
+)
+
+var (
+	AuthAllowedTokenScope = "AuthAllowedTokenScope"
+	tokenScopes           = []string{TokenScopeRead, TokenScopeBlogsWrite, TokenScopeCommentsWrite}
+)
+
+type CreatePersonalAccessTokenRequest struct {
+	TokenName     string   `json:"token_name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays *int     `json:"expires_in_days"`
+}
+
+func (h *Handler) GetMyTokensHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	personalAccessTokens, err := h.storage.GetUserPersonalAccessTokens(userId)
+	if err != nil {
+		log.Printf("failed to get user personal access tokens: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool                          `json:"success"`
+		Tokens  []storage.PersonalAccessToken `json:"tokens"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Tokens: personalAccessTokens}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// CreateMyTokenHandler the plain text token is only returned in this response, only its hash is stored
+func (h *Handler) CreateMyTokenHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var createTokenPayload CreatePersonalAccessTokenRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&createTokenPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokenName := strings.TrimSpace(createTokenPayload.TokenName)
+	if tokenName == "" {
+		writeJSONError(w, "token name is required", http.StatusBadRequest)
+		return
+	}
+
+	if utf8.RuneCountInString(tokenName) > MAX_PERSONAL_ACCESS_TOKEN_NAME_LENGTH {
+		writeJSONError(w, fmt.Sprintf("token name cannot be longer than %d characters", MAX_PERSONAL_ACCESS_TOKEN_NAME_LENGTH), http.StatusBadRequest)
+		return
+	}
+
+	if len(createTokenPayload.Scopes) == 0 {
+		writeJSONError(w, "atleast one scope is required", http.StatusBadRequest)
+		return
+	}
+
+	scopes := make([]string, 0, len(createTokenPayload.Scopes))
+	for _, scope := range createTokenPayload.Scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !slices.Contains(tokenScopes, scope) {
+			writeJSONError(w, fmt.Sprintf("invalid scope %q, valid scopes are %s", scope, strings.Join(tokenScopes, ", ")), http.StatusBadRequest)
+			return
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	expiresInDays := DEFAULT_PERSONAL_ACCESS_TOKEN_DAYS
+	if createTokenPayload.ExpiresInDays != nil {
+		expiresInDays = *createTokenPayload.ExpiresInDays
+	}
+
+	if expiresInDays < 1 || expiresInDays > MAX_PERSONAL_ACCESS_TOKEN_DAYS {
+		writeJSONError(w, fmt.Sprintf("expires_in_days must be between 1 and %d", MAX_PERSONAL_ACCESS_TOKEN_DAYS), http.StatusBadRequest)
+		return
+	}
+
+	activeTokensCount, err := h.storage.GetUserActivePersonalAccessTokensCount(userId)
+	if err != nil {
+		log.Printf("failed to get user active personal access tokens count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if activeTokensCount >= MAX_PERSONAL_ACCESS_TOKENS {
+		writeJSONError(w, fmt.Sprintf("cannot have more than %d active tokens", MAX_PERSONAL_ACCESS_TOKENS), http.StatusBadRequest)
+		return
+	}
+
+	plainTextToken, _, err := generateToken(32)
+	if err != nil {
+		log.Printf("failed to generate token: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// the prefix lets the auth middleware tell personal access tokens apart from session jwts
+	plainTextToken = PERSONAL_ACCESS_TOKEN_PREFIX + plainTextToken
+
+	personalAccessToken, err := h.storage.CreatePersonalAccessToken(userId, tokenName, hashToken(plainTextToken), scopes, time.Now().AddDate(0, 0, expiresInDays))
+	if err != nil {
+		log.Printf("failed to create personal access token: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success             bool                         `json:"success"`
+		Message             string                       `json:"message"`
+		Token               string                       `json:"token"`
+		PersonalAccessToken *storage.PersonalAccessToken `json:"personal_access_token"`
+	}
+
+	if err := writeJSON(w, Response{
+		Success:             true,
+		Message:             "token created, copy it now as it will not be shown again",
+		Token:               plainTextToken,
+		PersonalAccessToken: personalAccessToken,
+	}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) RevokeMyTokenHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	tokenId, err := strconv.ParseInt(chi.URLParam(r, "tokenId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param tokenId", http.StatusBadRequest)
+		return
+	}
+
+	personalAccessToken, err := h.storage.GetPersonalAccessTokenById(int(tokenId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "token does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// tokens of other users (and already revoked ones) are reported as not existing
+	if personalAccessToken.UserId != userId || personalAccessToken.RevokedAt != nil {
+		writeJSONError(w, "token does not exist", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.RevokePersonalAccessToken(personalAccessToken.Id); err != nil {
+		log.Printf("failed to revoke personal access token: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "token revoked successfully"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// AllowTokenScope declares the scopes a personal access token needs (any one of them) for the routes it is
+// used on, it has to run before AuthMiddleware / OptionalAuthMiddleware. routes without a declared scope
+// accept personal access tokens with the read scope for GET requests only. handlers of routes that allow
+// several scopes check with HasTokenScope what the token may do
+func (h *Handler) AllowTokenScope(scopes ...string) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), AuthAllowedTokenScope, scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireLoginSession rejects personal access tokens whatever their scopes, for the account management
+// routes (sessions, tokens, ...). it has to run after AuthMiddleware
+func (h *Handler) RequireLoginSession(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if tokenScopes, _ := r.Context().Value(AuthTokenScopes).([]string); tokenScopes != nil {
+			writeJSONError(w, "this route requires a login session", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HasTokenScope for handlers that need to check scopes themselves, requests authenticated
+// with a login session have every scope
+func HasTokenScope(r *http.Request, scope string) bool {
+
+	scopes, _ := r.Context().Value(AuthTokenScopes).([]string)
+	if scopes == nil {
+		return true
+	}
+
+	return slices.Contains(scopes, scope)
+}
+
+func isTokenScopeAllowed(r *http.Request, scopes []string) bool {
+
+	if allowedScopes, ok := r.Context().Value(AuthAllowedTokenScope).([]string); ok {
+		return slices.ContainsFunc(allowedScopes, func(allowedScope string) bool {
+			return slices.Contains(scopes, allowedScope)
+		})
+	}
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return slices.Contains(scopes, TokenScopeRead)
+	}
+
+	return false
+}
+
+// authenticatePersonalAccessToken returns errNotAuthenticated for unknown, revoked or expired tokens
+func (h *Handler) authenticatePersonalAccessToken(plainTextToken string) (int, []string, error) {
+
+	personalAccessToken, err := h.storage.GetActivePersonalAccessTokenByHash(hashToken(plainTextToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return -1, nil, errNotAuthenticated
+		}
+		return -1, nil, err
+	}
+
+	if err := h.storage.TouchPersonalAccessToken(personalAccessToken.Id); err != nil {
+		return -1, nil, err
+	}
+
+	scopes := []string(personalAccessToken.Scopes)
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	return personalAccessToken.UserId, scopes, nil
+}
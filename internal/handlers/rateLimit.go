@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// allowRateLimitedRequest fixed window counter in redis, the window starts with the first request for the key
+func (h *Handler) allowRateLimitedRequest(rateLimitKey string, maxRequests int64, window time.Duration) (bool, error) {
+
+	ctx := context.Background()
+
+	var requestsCountCmd *redis.IntCmd
+
+	//	one round trip, SET NX only creates the key (expiring with the window) on the first request
+	if _, err := h.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SetNX(ctx, rateLimitKey, 0, window)
+		requestsCountCmd = pipe.Incr(ctx, rateLimitKey)
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	return requestsCountCmd.Val() <= maxRequests, nil
+}
@@ -0,0 +1,435 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// every login starts a server side session (storage.UserSession):
+// a short lived access token (JWT with the session id as "sid") is sent on every request and a
+// long lived refresh token, rotated on every use, is exchanged for new access tokens at /auth/refresh
+
+const (
+	ACCESS_TOKEN_EXPIRATION  = time.Minute * 15
+	REFRESH_TOKEN_EXPIRATION = time.Hour * 24 * 30
+
+	AUTH_TOKEN_COOKIE    = "auth_token"
+	REFRESH_TOKEN_COOKIE = "refresh_token"
+
+	// the refresh token cookie is only sent to the auth endpoints
+	REFRESH_TOKEN_COOKIE_PATH = "/api/auth"
+
+	// with ?token_delivery=body the endpoints that start or refresh a session return the tokens in the
+	// response body instead of setting cookies (mobile apps, cli clients)
+	TOKEN_DELIVERY_QUERY_PARAM = "token_delivery"
+	TOKEN_DELIVERY_BODY        = "body"
+)
+
+// SessionTokens embedded in login and refresh responses when the tokens are delivered in the body
+type SessionTokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // seconds until the access token expires
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshTokenHandler a refresh token sent in the body (instead of the cookie) gets the new tokens back in the body
+func (h *Handler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+
+	refreshToken, isFromCookie := refreshTokenFromRequest(r)
+	if refreshToken == "" {
+		writeJSONError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	refreshTokenHash := hashToken(refreshToken)
+
+	newRefreshToken, newRefreshTokenHash, err := generateToken(32)
+	if err != nil {
+		log.Printf("failed to generate token: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userSession, err := h.storage.RotateRefreshToken(refreshTokenHash, newRefreshTokenHash, time.Now().Add(REFRESH_TOKEN_EXPIRATION))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			clearAuthCookies(w)
+			writeJSONError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, storage.ErrRefreshTokenReused) {
+			//	either the client replayed an old token or the token was stolen, the whole session is revoked
+			log.Printf("refresh token reuse detected, session revoked")
+			clearAuthCookies(w)
+			writeJSONError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		} else {
+			log.Printf("failed to rotate refresh token: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	accessToken, err := generateAccessToken(userSession.UserId, userSession.Id)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	sessionTokens := deliverSessionTokens(w, isBodyTokenDelivery(r) || !isFromCookie, accessToken, newRefreshToken)
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		*SessionTokens
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "refreshed session successfully", SessionTokens: sessionTokens}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// LogoutHandler revokes the current session (optional auth), the refresh token (cookie or body) identifies
+// the session when the access token has already expired
+func (h *Handler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+
+	sessionId, ok := r.Context().Value(AuthSessionId).(int)
+	if !ok {
+		sessionId = -1
+
+		if refreshToken, _ := refreshTokenFromRequest(r); refreshToken != "" {
+			var err error
+			sessionId, err = h.storage.GetUserSessionIdByRefreshToken(hashToken(refreshToken))
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				log.Printf("failed to get user session by refresh token: %v\n", err)
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	if sessionId != -1 {
+		if err := h.storage.RevokeUserSession(sessionId); err != nil {
+			log.Printf("failed to revoke user session: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	clearAuthCookies(w)
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "logged out successfully"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// LogoutEverywhereHandler revokes every session of the authenticated user, including the current one
+func (h *Handler) LogoutEverywhereHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.storage.RevokeUserSessions(userId); err != nil {
+		log.Printf("failed to revoke user sessions: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	clearAuthCookies(w)
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "logged out of all sessions successfully"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// GetMySessionsHandler active sessions (logins) of the authenticated user, is_current marks the session of this request
+func (h *Handler) GetMySessionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	currentSessionId, _ := r.Context().Value(AuthSessionId).(int)
+
+	userSessions, err := h.storage.GetUserActiveSessions(userId)
+	if err != nil {
+		log.Printf("failed to get user sessions: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Session struct {
+		storage.UserSession
+		IsCurrent bool `json:"is_current"`
+	}
+
+	sessions := make([]Session, 0, len(userSessions))
+	for _, userSession := range userSessions {
+		sessions = append(sessions, Session{UserSession: userSession, IsCurrent: userSession.Id == currentSessionId})
+	}
+
+	type Response struct {
+		Success  bool      `json:"success"`
+		Sessions []Session `json:"sessions"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Sessions: sessions}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// RevokeMySessionHandler logs a single session out, revoking the current session also clears the auth cookies
+func (h *Handler) RevokeMySessionHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	currentSessionId, _ := r.Context().Value(AuthSessionId).(int)
+
+	sessionId, err := strconv.ParseInt(chi.URLParam(r, "sessionId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param sessionId", http.StatusBadRequest)
+		return
+	}
+
+	userSession, err := h.storage.GetActiveUserSession(int(sessionId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "session does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// sessions of other users are reported as not existing
+	if userSession.UserId != userId {
+		writeJSONError(w, "session does not exist", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.RevokeUserSession(userSession.Id); err != nil {
+		log.Printf("failed to revoke user session: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if userSession.Id == currentSessionId {
+		clearAuthCookies(w)
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "session revoked successfully"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// startUserSession creates a session for the user and sets its access and refresh token cookies,
+// with ?token_delivery=body no cookies are set and the tokens are returned for the response body
+func (h *Handler) startUserSession(w http.ResponseWriter, r *http.Request, userId int) (*SessionTokens, error) {
+
+	refreshToken, refreshTokenHash, err := generateToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	userSession, err := h.storage.CreateUserSession(userId, refreshTokenHash, time.Now().Add(REFRESH_TOKEN_EXPIRATION), h.clientIpAddress(r), r.UserAgent())
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := generateAccessToken(userId, userSession.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	//	logging in again within the grace period cancels a scheduled account deletion (DELETE /me)
+	if _, err := h.storage.CancelUserDeletion(userId); err != nil {
+		return nil, err
+	}
+
+	return deliverSessionTokens(w, isBodyTokenDelivery(r), accessToken, refreshToken), nil
+}
+
+func isBodyTokenDelivery(r *http.Request) bool {
+	return r.URL.Query().Get(TOKEN_DELIVERY_QUERY_PARAM) == TOKEN_DELIVERY_BODY
+}
+
+// deliverSessionTokens sets the auth cookies, or returns the tokens to embed in the response when inBody
+func deliverSessionTokens(w http.ResponseWriter, inBody bool, accessToken string, refreshToken string) *SessionTokens {
+
+	if !inBody {
+		setAuthCookies(w, accessToken, refreshToken)
+		return nil
+	}
+
+	return &SessionTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(ACCESS_TOKEN_EXPIRATION.Seconds()),
+	}
+}
+
+// refreshTokenFromRequest the refresh token cookie, else {"refresh_token": "..."} from the body for
+// clients without cookies, isFromCookie tells which one was used
+func refreshTokenFromRequest(r *http.Request) (refreshToken string, isFromCookie bool) {
+
+	if cookie, err := r.Cookie(REFRESH_TOKEN_COOKIE); err == nil && cookie.Value != "" {
+		return cookie.Value, true
+	}
+
+	var refreshTokenPayload RefreshTokenRequest
+
+	//	an empty or invalid body is the same as no refresh token
+	if err := json.NewDecoder(r.Body).Decode(&refreshTokenPayload); err != nil {
+		return "", false
+	}
+
+	return strings.TrimSpace(refreshTokenPayload.RefreshToken), false
+}
+
+// isUserSessionValid the access token's session ("sid") should be active and belong to the token's user,
+// tokens issued before sessions existed have no "sid" and are rejected
+func (h *Handler) isUserSessionValid(userId int, claims jwt.MapClaims) (int, bool, error) {
+
+	sessionIdFloat, ok := claims["sid"].(float64)
+	if !ok {
+		return -1, false, nil
+	}
+	sessionId := int(sessionIdFloat)
+
+	userSession, err := h.storage.GetActiveUserSession(sessionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return -1, false, nil
+		}
+		return -1, false, err
+	}
+
+	if userSession.UserId != userId {
+		return -1, false, nil
+	}
+
+	if err := h.storage.TouchUserSession(sessionId); err != nil {
+		return -1, false, err
+	}
+
+	return sessionId, true, nil
+}
+
+func generateAccessToken(userId int, sessionId int) (string, error) {
+
+	claims := jwt.MapClaims{
+		"sub": userId,
+		"sid": sessionId,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(ACCESS_TOKEN_EXPIRATION).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString(JWT_SECRET)
+}
+
+func authCookieSameSite() http.SameSite {
+
+	if os.Getenv("GO_ENV") == "development" {
+		return http.SameSiteLaxMode
+	}
+
+	return http.SameSiteNoneMode
+}
+
+func setAuthCookies(w http.ResponseWriter, accessToken string, refreshToken string) {
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     AUTH_TOKEN_COOKIE,
+		Value:    accessToken,
+		HttpOnly: true,
+		Path:     "/",
+		Secure:   os.Getenv("GO_ENV") == "production",
+		MaxAge:   int(ACCESS_TOKEN_EXPIRATION.Seconds()),
+		SameSite: authCookieSameSite(),
+	})
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     REFRESH_TOKEN_COOKIE,
+		Value:    refreshToken,
+		HttpOnly: true,
+		Path:     REFRESH_TOKEN_COOKIE_PATH,
+		Secure:   os.Getenv("GO_ENV") == "production",
+		MaxAge:   int(REFRESH_TOKEN_EXPIRATION.Seconds()),
+		SameSite: authCookieSameSite(),
+	})
+}
+
+func clearAuthCookies(w http.ResponseWriter) {
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     AUTH_TOKEN_COOKIE,
+		Value:    "",
+		HttpOnly: true,
+		Path:     "/",
+		Secure:   os.Getenv("GO_ENV") == "production",
+		MaxAge:   -1,
+		SameSite: authCookieSameSite(),
+	})
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     REFRESH_TOKEN_COOKIE,
+		Value:    "",
+		HttpOnly: true,
+		Path:     REFRESH_TOKEN_COOKIE_PATH,
+		Secure:   os.Getenv("GO_ENV") == "production",
+		MaxAge:   -1,
+		SameSite: authCookieSameSite(),
+	})
+}
+
+// hashToken tokens are only stored as sha256 hex digests (same as generateToken's hashed token)
+func hashToken(plainTextToken string) string {
+
+	hashedToken := sha256.Sum256([]byte(plainTextToken))
+
+	return hex.EncodeToString(hashedToken[:])
+}
@@ -0,0 +1,545 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/dhruv15803/go-blog-app/utils"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TOTP_ISSUER                     = "Go Blog"
+	TWO_FACTOR_CHALLENGE_EXPIRATION = time.Minute * 5
+	MAX_TWO_FACTOR_ATTEMPTS         = 5
+	RECOVERY_CODES_COUNT            = 10
+)
+
+type TwoFactorPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"` // a 6 digit totp code or a recovery code
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+func (h *Handler) GetMyTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	isEnabled, err := h.storage.IsUserTwoFactorEnabled(userId)
+	if err != nil {
+		log.Printf("failed to check user two factor: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	recoveryCodesRemaining := 0
+	if isEnabled {
+		recoveryCodesRemaining, err = h.storage.GetUnusedRecoveryCodesCount(userId)
+		if err != nil {
+			log.Printf("failed to get unused recovery codes count: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success                bool `json:"success"`
+		Enabled                bool `json:"enabled"`
+		RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Enabled: isEnabled, RecoveryCodesRemaining: recoveryCodesRemaining}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// EnrollTwoFactorHandler generates a new totp secret, 2fa is only enabled once a code for it is confirmed
+func (h *Handler) EnrollTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var enrollPayload TwoFactorPasswordRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&enrollPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(strings.TrimSpace(enrollPayload.Password))); err != nil {
+		writeJSONError(w, "invalid password", http.StatusBadRequest)
+		return
+	}
+
+	isEnabled, err := h.storage.IsUserTwoFactorEnabled(userId)
+	if err != nil {
+		log.Printf("failed to check user two factor: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if isEnabled {
+		writeJSONError(w, "two factor authentication is already enabled", http.StatusBadRequest)
+		return
+	}
+
+	totpSecret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("failed to generate totp secret: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := h.storage.StartUserTwoFactorEnrolment(userId, totpSecret); err != nil {
+		log.Printf("failed to start user two factor enrolment: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success    bool   `json:"success"`
+		Message    string `json:"message"`
+		Secret     string `json:"secret"`
+		OtpAuthUri string `json:"otpauth_uri"` // render as a qr code for authenticator apps
+	}
+
+	if err := writeJSON(w, Response{
+		Success:    true,
+		Message:    "scan the qr code with an authenticator app and confirm with a code",
+		Secret:     totpSecret,
+		OtpAuthUri: utils.TOTPAuthURI(totpSecret, TOTP_ISSUER, user.Email),
+	}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// ConfirmTwoFactorHandler enables 2fa with a code from the enrolled secret and returns the recovery codes (shown once)
+func (h *Handler) ConfirmTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var confirmPayload TwoFactorCodeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&confirmPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userTwoFactor, err := h.storage.GetUserTwoFactor(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "two factor enrolment not started", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to get user two factor: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if userTwoFactor.EnabledAt != nil {
+		writeJSONError(w, "two factor authentication is already enabled", http.StatusBadRequest)
+		return
+	}
+
+	step, isValid := utils.ValidateTOTPCode(userTwoFactor.TotpSecret, normalizeTwoFactorCode(confirmPayload.Code), time.Now())
+	if !isValid {
+		writeJSONError(w, "invalid code", http.StatusBadRequest)
+		return
+	}
+
+	recoveryCodes, recoveryCodeHashes, err := generateRecoveryCodes()
+	if err != nil {
+		log.Printf("failed to generate recovery codes: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.storage.EnableUserTwoFactor(userId, step, recoveryCodeHashes); err != nil {
+		log.Printf("failed to enable user two factor: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success       bool     `json:"success"`
+		Message       string   `json:"message"`
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	if err := writeJSON(w, Response{
+		Success:       true,
+		Message:       "two factor authentication enabled, store the recovery codes somewhere safe",
+		RecoveryCodes: recoveryCodes,
+	}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) DisableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var disablePayload DisableTwoFactorRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&disablePayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if isStaffRole(user.Role) && h.requireAdminTwoFactor {
+		writeJSONError(w, "two factor authentication is required for staff accounts", http.StatusBadRequest)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(strings.TrimSpace(disablePayload.Password))); err != nil {
+		writeJSONError(w, "invalid password", http.StatusBadRequest)
+		return
+	}
+
+	isValid, retryAfter, err := h.verifyTwoFactorCode(userId, disablePayload.Code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "two factor authentication is not enabled", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to verify two factor code: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if retryAfter > 0 {
+		writeRetryAfterError(w, "too many invalid codes, please try again later", retryAfter)
+		return
+	}
+
+	if !isValid {
+		writeJSONError(w, "invalid code", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.DisableUserTwoFactor(userId); err != nil {
+		log.Printf("failed to disable user two factor: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "two factor authentication disabled"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// RegenerateRecoveryCodesHandler replaces all recovery codes (used or not) with new ones
+func (h *Handler) RegenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var regeneratePayload TwoFactorCodeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&regeneratePayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	isValid, retryAfter, err := h.verifyTwoFactorCode(userId, regeneratePayload.Code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "two factor authentication is not enabled", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to verify two factor code: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if retryAfter > 0 {
+		writeRetryAfterError(w, "too many invalid codes, please try again later", retryAfter)
+		return
+	}
+
+	if !isValid {
+		writeJSONError(w, "invalid code", http.StatusBadRequest)
+		return
+	}
+
+	recoveryCodes, recoveryCodeHashes, err := generateRecoveryCodes()
+	if err != nil {
+		log.Printf("failed to generate recovery codes: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.storage.ReplaceRecoveryCodes(userId, recoveryCodeHashes); err != nil {
+		log.Printf("failed to replace recovery codes: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success       bool     `json:"success"`
+		Message       string   `json:"message"`
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "recovery codes regenerated", RecoveryCodes: recoveryCodes}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// LoginTwoFactorHandler second step of the login, exchanges the challenge token from LoginUserHandler
+// and a totp or recovery code for a session
+func (h *Handler) LoginTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+
+	var loginTwoFactorPayload LoginTwoFactorRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&loginTwoFactorPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(loginTwoFactorPayload.ChallengeToken) == "" || strings.TrimSpace(loginTwoFactorPayload.Code) == "" {
+		writeJSONError(w, "challenge token and code are required", http.StatusBadRequest)
+		return
+	}
+
+	challengeTokenHash := hashToken(strings.TrimSpace(loginTwoFactorPayload.ChallengeToken))
+
+	userId, err := h.storage.AttemptTwoFactorChallenge(challengeTokenHash, MAX_TWO_FACTOR_ATTEMPTS)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "invalid or expired challenge token, please login again", http.StatusUnauthorized)
+			return
+		} else {
+			log.Printf("failed to attempt two factor challenge: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	isValid, retryAfter, err := h.verifyTwoFactorCode(userId, loginTwoFactorPayload.Code)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to verify two factor code: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if retryAfter > 0 {
+		writeRetryAfterError(w, "too many invalid codes, please try again later", retryAfter)
+		return
+	}
+
+	if !isValid {
+		writeJSONError(w, "invalid code", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.DeleteTwoFactorChallenge(challengeTokenHash); err != nil {
+		log.Printf("failed to delete two factor challenge: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	sessionTokens, err := h.startUserSession(w, r, user.Id)
+	if err != nil {
+		log.Printf("failed to start user session: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool         `json:"success"`
+		Message string       `json:"message"`
+		User    storage.User `json:"user"`
+		*SessionTokens
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "logged in user successfully", User: *user, SessionTokens: sessionTokens}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// startTwoFactorChallenge returns the plain text challenge token for the second login step
+func (h *Handler) startTwoFactorChallenge(userId int) (string, error) {
+
+	challengeToken, challengeTokenHash, err := generateToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	if err := h.storage.CreateTwoFactorChallenge(userId, challengeTokenHash, time.Now().Add(TWO_FACTOR_CHALLENGE_EXPIRATION)); err != nil {
+		return "", err
+	}
+
+	return challengeToken, nil
+}
+
+// verifyTwoFactorCode accepts a totp code (each time step only once) or an unused recovery code,
+// returns sql.ErrNoRows if the user has not enabled 2fa. invalid codes count against twoFactorThrottle,
+// while the user is locked out no code is checked and retryAfter is the time left
+func (h *Handler) verifyTwoFactorCode(userId int, code string) (isValid bool, retryAfter time.Duration, err error) {
+
+	throttleSubject := strconv.Itoa(userId)
+
+	retryAfter, err = twoFactorThrottle.retryAfter(h.redisClient, throttleSubject)
+	if err != nil || retryAfter > 0 {
+		return false, retryAfter, err
+	}
+
+	userTwoFactor, err := h.storage.GetUserTwoFactor(userId)
+	if err != nil {
+		return false, 0, err
+	}
+
+	if userTwoFactor.EnabledAt == nil {
+		return false, 0, sql.ErrNoRows
+	}
+
+	code = normalizeTwoFactorCode(code)
+
+	if len(code) == utils.TOTP_DIGITS {
+		step, isTotpValid := utils.ValidateTOTPCode(userTwoFactor.TotpSecret, code, time.Now())
+		if isTotpValid {
+			isValid, err = h.storage.UseTotpStep(userId, step)
+		}
+	} else {
+		isValid, err = h.storage.UseRecoveryCode(userId, hashToken(code))
+	}
+
+	if err != nil || isValid {
+		return isValid, 0, err
+	}
+
+	isLockedOut, err := twoFactorThrottle.recordFailure(h.redisClient, throttleSubject)
+	if err != nil {
+		return false, 0, err
+	}
+
+	//	the open challenges go with the lockout, logging in again needs the password again
+	if isLockedOut {
+		if err := h.storage.DeleteUserTwoFactorChallenges(userId); err != nil {
+			return false, 0, err
+		}
+	}
+
+	return false, 0, nil
+}
+
+// isTwoFactorSetupRequired admins and the other staff roles have to enable 2fa when REQUIRE_ADMIN_2FA is set
+func (h *Handler) isTwoFactorSetupRequired(user *storage.User) (bool, error) {
+
+	if !h.requireAdminTwoFactor || !isStaffRole(user.Role) {
+		return false, nil
+	}
+
+	isEnabled, err := h.storage.IsUserTwoFactorEnabled(user.Id)
+	if err != nil {
+		return false, err
+	}
+
+	return !isEnabled, nil
+}
+
+// normalizeTwoFactorCode codes are accepted with spaces / dashes and in any case
+func normalizeTwoFactorCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+}
+
+// generateRecoveryCodes plain text codes (xxxx-xxxx-xxxx-xxxx) for the user and their hashes for storage
+func generateRecoveryCodes() ([]string, []string, error) {
+
+	recoveryCodes := make([]string, 0, RECOVERY_CODES_COUNT)
+	recoveryCodeHashes := make([]string, 0, RECOVERY_CODES_COUNT)
+
+	for range RECOVERY_CODES_COUNT {
+		codeBytes := make([]byte, 8)
+		if _, err := rand.Read(codeBytes); err != nil {
+			return nil, nil, err
+		}
+
+		code := hex.EncodeToString(codeBytes)
+		recoveryCodes = append(recoveryCodes, fmt.Sprintf("%s-%s-%s-%s", code[0:4], code[4:8], code[8:12], code[12:16]))
+		recoveryCodeHashes = append(recoveryCodeHashes, hashToken(code))
+	}
+
+	return recoveryCodes, recoveryCodeHashes, nil
+}
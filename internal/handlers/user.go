@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// follow/unfollow toggle for {userId} by the authenticated user
+func (h *Handler) FollowUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	authUserId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	authUser, err := h.storage.GetUserById(authUserId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user does not exist", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	userId, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param userId", http.StatusBadRequest)
+		return
+	}
+
+	//	unverified accounts and the deleted user placeholder cannot be followed
+	userToFollow, err := h.storage.GetVerifiedUserById(int(userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user to follow not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if authUser.Id == userToFollow.Id {
+		writeJSONError(w, "cannot follow yourself", http.StatusBadRequest)
+		return
+	}
+
+	existingFollow, err := h.storage.GetFollow(authUser.Id, userToFollow.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if existingFollow == nil {
+
+		follow, err := h.storage.CreateFollow(authUser.Id, userToFollow.Id)
+		if err != nil {
+			log.Printf("failed to create follow: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		type Response struct {
+			Success bool           `json:"success"`
+			Message string         `json:"message"`
+			Follow  storage.Follow `json:"follow"`
+		}
+
+		if err := writeJSON(w, Response{Success: true, Message: "followed user", Follow: *follow}, http.StatusCreated); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+	} else {
+
+		if err := h.storage.RemoveFollow(existingFollow.FollowerId, existingFollow.FollowingId); err != nil {
+			log.Printf("failed to remove follow: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		type Response struct {
+			Success bool   `json:"success"`
+			Message string `json:"message"`
+		}
+
+		if err := writeJSON(w, Response{Success: true, Message: "unfollowed user"}, http.StatusOK); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+	}
+}
+
+func (h *Handler) GetUserFollowersHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param userId", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.storage.GetUserById(int(userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var page int
+	var limit int
+
+	if r.URL.Query().Get("page") == "" {
+		page = 1
+	} else {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+			return
+		}
+	}
+	if r.URL.Query().Get("limit") == "" {
+		limit = 10
+	} else {
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil {
+			writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	skip := page*limit - limit
+
+	followers, err := h.storage.GetUserFollowers(user.Id, skip, limit)
+	if err != nil {
+		log.Printf("failed to get user followers: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalFollowersCount, err := h.storage.GetUserFollowersCount(user.Id)
+	if err != nil {
+		log.Printf("failed to get user followers count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalFollowersCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool                  `json:"success"`
+		Followers []storage.UserProfile `json:"followers"`
+		NoOfPages int                   `json:"no_of_pages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Followers: followers, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) GetUserFollowingHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param userId", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.storage.GetUserById(int(userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var page int
+	var limit int
+
+	if r.URL.Query().Get("page") == "" {
+		page = 1
+	} else {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+			return
+		}
+	}
+	if r.URL.Query().Get("limit") == "" {
+		limit = 10
+	} else {
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil {
+			writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	skip := page*limit - limit
+
+	following, err := h.storage.GetUserFollowing(user.Id, skip, limit)
+	if err != nil {
+		log.Printf("failed to get user following: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalFollowingCount, err := h.storage.GetUserFollowingCount(user.Id)
+	if err != nil {
+		log.Printf("failed to get user following count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalFollowingCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool                  `json:"success"`
+		Following []storage.UserProfile `json:"following"`
+		NoOfPages int                   `json:"no_of_pages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Following: following, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// public profile of a user, {userId} can also be the user's username
+func (h *Handler) GetUserProfileHandler(w http.ResponseWriter, r *http.Request) {
+
+	userIdOrUsername := chi.URLParam(r, "userId")
+
+	var userProfile *storage.UserProfile
+	var err error
+
+	if userId, parseErr := strconv.ParseInt(userIdOrUsername, 10, 64); parseErr == nil {
+		userProfile, err = h.storage.GetUserProfileById(int(userId))
+	} else {
+		userProfile, err = h.storage.GetUserProfileByUsername(userIdOrUsername)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to get user profile: %v\n", err)
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success     bool                `json:"success"`
+		UserProfile storage.UserProfile `json:"user_profile"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, UserProfile: *userProfile}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// blogs written by {userId} (optional auth), ?status=published|draft|scheduled|archived|all
+// only the author can list non-published blogs, the author sees all their blogs by default
+func (h *Handler) GetUserBlogsHandler(w http.ResponseWriter, r *http.Request) {
+
+	hasAuthUser := true
+	authUserId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		hasAuthUser = false
+	}
+
+	userId, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid request param userId", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.storage.GetUserById(int(userId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	isBlogsAuthor := hasAuthUser && authUserId == user.Id
+
+	var blogStatuses []storage.BlogStatus
+
+	switch status := r.URL.Query().Get("status"); status {
+	case "":
+		if isBlogsAuthor {
+			blogStatuses = allBlogStatuses
+		} else {
+			blogStatuses = []storage.BlogStatus{storage.BlogStatusPublished}
+		}
+	case "all":
+		if !isBlogsAuthor {
+			writeJSONError(w, "unauthorized to view non published blogs", http.StatusUnauthorized)
+			return
+		}
+		blogStatuses = allBlogStatuses
+	case string(storage.BlogStatusPublished):
+		blogStatuses = []storage.BlogStatus{storage.BlogStatusPublished}
+	case string(storage.BlogStatusDraft), string(storage.BlogStatusScheduled), string(storage.BlogStatusArchived):
+		if !isBlogsAuthor {
+			writeJSONError(w, "unauthorized to view non published blogs", http.StatusUnauthorized)
+			return
+		}
+		blogStatuses = []storage.BlogStatus{storage.BlogStatus(status)}
+	default:
+		writeJSONError(w, "invalid query param status", http.StatusBadRequest)
+		return
+	}
+
+	var page int
+	var limit int
+
+	if r.URL.Query().Get("page") == "" {
+		page = 1
+	} else {
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+			return
+		}
+	}
+	if r.URL.Query().Get("limit") == "" {
+		limit = 10
+	} else {
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil {
+			writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	skip := page*limit - limit
+
+	//	a suspended or banned author's blogs are hidden from everyone but the author, like in the feeds
+	blogs, err := h.storage.GetBlogsByAuthor(user.Id, blogStatuses, !isBlogsAuthor, skip, limit)
+	if err != nil {
+		log.Printf("failed to get blogs by author: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalBlogsCount, err := h.storage.GetBlogsByAuthorCount(user.Id, blogStatuses, !isBlogsAuthor)
+	if err != nil {
+		log.Printf("failed to get blogs by author count: %v\n", err)
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := int(math.Ceil(float64(totalBlogsCount) / float64(limit)))
+
+	type Response struct {
+		Success   bool                       `json:"success"`
+		Blogs     []storage.BlogWithMetaData `json:"blogs"`
+		NoOfPages int                        `json:"no_of_pages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Blogs: blogs, NoOfPages: noOfPages}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+var allBlogStatuses = []storage.BlogStatus{
+	storage.BlogStatusDraft,
+	storage.BlogStatusScheduled,
+	storage.BlogStatusPublished,
+	storage.BlogStatusArchived,
+}
@@ -0,0 +1,98 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// github oauth apps do not issue id tokens, the identity is read from the rest api with the access token
+const (
+	GITHUB_AUTHORIZE_URL = "https://github.com/login/oauth/authorize"
+	GITHUB_TOKEN_URL     = "https://github.com/login/oauth/access_token"
+	GITHUB_API_URL       = "https://api.github.com"
+)
+
+type GitHubProvider struct {
+	cfg ProviderConfig
+}
+
+func newGitHubProvider(cfg ProviderConfig) *GitHubProvider {
+
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"read:user", "user:email"}
+	}
+
+	return &GitHubProvider{cfg: cfg}
+}
+
+func (p *GitHubProvider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *GitHubProvider) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+
+	params := url.Values{}
+	params.Set("client_id", p.cfg.ClientId)
+	params.Set("redirect_uri", p.cfg.RedirectUrl)
+	params.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	params.Set("state", state)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+
+	return appendQuery(GITHUB_AUTHORIZE_URL, params), nil
+}
+
+func (p *GitHubProvider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*Identity, error) {
+
+	token, err := exchangeCode(ctx, GITHUB_TOKEN_URL, p.cfg, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.AccessToken == "" {
+		return nil, errors.New("token response has no access_token")
+	}
+
+	var githubUser struct {
+		Id        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarUrl string `json:"avatar_url"`
+	}
+
+	if err := getJSON(ctx, GITHUB_API_URL+"/user", token.AccessToken, &githubUser); err != nil {
+		return nil, err
+	}
+
+	var githubEmails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+
+	if err := getJSON(ctx, GITHUB_API_URL+"/user/emails", token.AccessToken, &githubEmails); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Subject: strconv.FormatInt(githubUser.Id, 10),
+		Name:    githubUser.Name,
+		Picture: githubUser.AvatarUrl,
+	}
+	if identity.Name == "" {
+		identity.Name = githubUser.Login
+	}
+
+	for _, githubEmail := range githubEmails {
+		if githubEmail.Primary {
+			identity.Email = strings.ToLower(strings.TrimSpace(githubEmail.Email))
+			identity.EmailVerified = githubEmail.Verified
+			break
+		}
+	}
+
+	return identity, nil
+}
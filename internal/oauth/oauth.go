@@ -0,0 +1,164 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Identity the user as asserted by a provider, Subject is the provider's stable user id
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// Provider an authorization code (+ PKCE) login provider, OIDCProvider works with any
+// OpenID Connect issuer (google, keycloak, a local mock server ...), GitHubProvider with github's oauth apps
+type Provider interface {
+	Name() string
+	// AuthCodeURL the url the user is redirected to, nonce is ignored by providers without id tokens
+	AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error)
+	// Exchange redeems the authorization code and returns the verified identity of the user
+	Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*Identity, error)
+}
+
+type ProviderConfig struct {
+	Name         string
+	Type         string // "oidc" or "github"
+	IssuerUrl    string // oidc only, discovery is fetched from <issuer>/.well-known/openid-configuration
+	ClientId     string
+	ClientSecret string
+	RedirectUrl  string
+	Scopes       []string
+}
+
+const (
+	ProviderTypeOIDC   = "oidc"
+	ProviderTypeGitHub = "github"
+)
+
+var httpClient = &http.Client{Timeout: time.Second * 10}
+
+func NewProvider(cfg ProviderConfig) (Provider, error) {
+
+	if cfg.Name == "" || cfg.ClientId == "" || cfg.RedirectUrl == "" {
+		return nil, errors.New("provider name, client id and redirect url are required")
+	}
+
+	switch cfg.Type {
+	case ProviderTypeOIDC:
+		if cfg.IssuerUrl == "" {
+			return nil, fmt.Errorf("issuer url is required for oidc provider %s", cfg.Name)
+		}
+		return newOIDCProvider(cfg), nil
+	case ProviderTypeGitHub:
+		return newGitHubProvider(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown provider type %q for provider %s", cfg.Type, cfg.Name)
+	}
+}
+
+// RandomString url safe random string for state, nonce and pkce code verifiers
+func RandomString() (string, error) {
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// PKCEChallenge S256 code challenge of a code verifier (RFC 7636)
+func PKCEChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// tokenResponse token endpoint response (RFC 6749 section 5.1), id_token for oidc
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IdToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func exchangeCode(ctx context.Context, tokenUrl string, cfg ProviderConfig, code string, codeVerifier string) (*tokenResponse, error) {
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", cfg.RedirectUrl)
+	form.Set("client_id", cfg.ClientId)
+	form.Set("code_verifier", codeVerifier)
+	if cfg.ClientSecret != "" {
+		form.Set("client_secret", cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token tokenResponse
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("failed to decode token response (status %d): %w", res.StatusCode, err)
+	}
+
+	if token.Error != "" {
+		return nil, fmt.Errorf("token endpoint error: %s %s", token.Error, token.ErrorDescription)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint responded with status %d", res.StatusCode)
+	}
+
+	return &token, nil
+}
+
+// getJSON GET request decoding a json response, bearerToken is optional
+func getJSON(ctx context.Context, getUrl string, bearerToken string, v any) error {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getUrl, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s responded with status %d", getUrl, res.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(v)
+}
@@ -1,18 +1,21 @@
 package storage
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"github.com/lib/pq"
 	"time"
 )
 
 type BlogStatus string
 
-// 'draft','published','archived'
+// 'draft','published','archived','scheduled'
 const (
 	BlogStatusDraft      BlogStatus = "draft"
 	BlogStatusPublished  BlogStatus = "published"
 	BlogStatusArchived   BlogStatus = "archived"
+	BlogStatusScheduled  BlogStatus = "scheduled"
 	BlogLikesCountWt                = 0.3
 	BlogCommentsCountWt             = 0.5
 	BlogBookmarksCountWt            = 0.2
@@ -27,10 +30,15 @@ type Blog struct {
 	BlogStatus      BlogStatus      `db:"blog_status" json:"blog_status"`
 	BlogAuthorId    int             `db:"blog_author_id" json:"blog_author_id"`
 	PublishedAt     *string         `db:"published_at" json:"published_at"`
+	PublishAt       *string         `db:"publish_at" json:"publish_at"` // set while blog_status is 'scheduled'
 	BlogCreatedAt   string          `db:"blog_created_at" json:"blog_created_at"`
 	BlogUpdatedAt   *string         `db:"blog_updated_at" json:"blog_updated_at"`
+	BlogVersion     int             `db:"blog_version" json:"blog_version"`
 }
 
+// ErrBlogVersionConflict the blog was modified since the caller read blogVersion
+var ErrBlogVersionConflict = errors.New("blog version conflict")
+
 type BlogTopic struct {
 	BlogId  int `db:"blog_id" json:"blog_id"`
 	TopicId int `db:"topic_id" json:"topic_id"`
@@ -69,7 +77,7 @@ func (s *Storage) CreateBlogWithTopics(blogTitle string, blogDescription string,
 	var blog Blog
 	insertBlogQuery := `INSERT INTO blogs(blog_title,blog_description,blog_content,blog_thumbnail,blog_status,blog_author_id,published_at) 
 	VALUES($1,$2,$3,$4,$5,$6,$7) RETURNING id,blog_title,blog_description,blog_content,blog_thumbnail,blog_status,
-	blog_author_id,published_at,blog_created_at,blog_updated_at`
+	blog_author_id,published_at,blog_created_at,blog_updated_at,blog_version,publish_at`
 
 	var publishedAtArg any
 	if blogStatus == BlogStatusPublished {
@@ -131,7 +139,7 @@ func (s *Storage) CreateBlog(blogTitle string, blogDescription string, blogConte
 	var blog Blog
 	insertBlogQuery := `INSERT INTO blogs(blog_title,blog_description,blog_content,blog_thumbnail,blog_status,blog_author_id,published_at) 
 	VALUES($1,$2,$3,$4,$5,$6,$7) RETURNING id,blog_title,blog_description,blog_content,blog_thumbnail,blog_status,
-	blog_author_id,published_at,blog_created_at,blog_updated_at`
+	blog_author_id,published_at,blog_created_at,blog_updated_at,blog_version,publish_at`
 
 	var publishedAtArg any
 	if blogStatus == BlogStatusPublished {
@@ -161,7 +169,7 @@ func (s *Storage) GetBlogById(blogId int) (*Blog, error) {
 
 	var blog Blog
 
-	query := `SELECT id,id, blog_title, blog_description, blog_content, blog_thumbnail, blog_status, blog_author_id, published_at, blog_created_at, blog_updated_at 
+	query := `SELECT id,id, blog_title, blog_description, blog_content, blog_thumbnail, blog_status, blog_author_id, published_at, blog_created_at, blog_updated_at, blog_version, publish_at 
 	FROM blogs WHERE id=$1`
 
 	if err := s.db.QueryRowx(query, blogId).StructScan(&blog); err != nil {
@@ -171,11 +179,74 @@ func (s *Storage) GetBlogById(blogId int) (*Blog, error) {
 	return &blog, nil
 }
 
-func (s *Storage) DeleteBlogById(blogId int) error {
+// GetBlogWithMetaDataById blog with author, topics and likes/comments/bookmarks counts (any blog status)
+func (s *Storage) GetBlogWithMetaDataById(blogId int) (*BlogWithMetaData, error) {
+
+	var blog BlogWithMetaData
+
+	query := `SELECT
+  b.id,
+  b.blog_title,
+  b.blog_description,
+  b.blog_content,
+  b.blog_thumbnail,
+  b.blog_status,
+  b.blog_author_id,
+  b.published_at,
+  b.blog_created_at,
+  b.blog_updated_at,
+  b.blog_version,
+  b.publish_at,
+  u.id,
+  u.email,
+  u.username,
+  u.password,
+  u.name,
+  u.profile_img,
+  u.is_verified,
+  u.role,
+  u.created_at,
+  u.updated_at,
+  COUNT(DISTINCT bl.liked_by_id) AS blog_likes_count,
+  COUNT(DISTINCT bb.bookmarked_by_id) AS blog_bookmarks_count,
+  COUNT(DISTINCT bc.id) AS blog_comments_count
+FROM
+  blogs AS b
+  INNER JOIN users AS u ON b.blog_author_id = u.id
+  LEFT JOIN blog_likes AS bl ON b.id = bl.liked_blog_id
+  LEFT JOIN blog_bookmarks AS bb ON b.id = bb.bookmarked_blog_id
+  LEFT JOIN blog_comments AS bc ON b.id = bc.blog_id
+  AND bc.parent_comment_id IS NULL
+WHERE
+  b.id = $1
+GROUP BY
+  b.id,
+  u.id`
+
+	if err := s.db.QueryRowx(query, blogId).Scan(&blog.Id, &blog.BlogTitle, &blog.BlogDescription, &blog.BlogContent,
+		&blog.BlogThumbnail, &blog.BlogStatus, &blog.BlogAuthorId, &blog.PublishedAt, &blog.BlogCreatedAt, &blog.BlogUpdatedAt, &blog.BlogVersion, &blog.PublishAt,
+		&blog.BlogAuthor.Id, &blog.BlogAuthor.Email, &blog.BlogAuthor.Username, &blog.BlogAuthor.Password,
+		&blog.BlogAuthor.Name, &blog.BlogAuthor.ProfileImg, &blog.BlogAuthor.IsVerified, &blog.BlogAuthor.Role,
+		&blog.BlogAuthor.CreatedAt, &blog.BlogAuthor.UpdatedAt, &blog.BlogLikesCount, &blog.BlogBookmarksCount, &blog.BlogCommentsCount); err != nil {
+		return nil, err
+	}
+
+	topics, err := s.GetBlogTopics(blog.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	blog.BlogTopics = topics
+
+	return &blog, nil
+}
+
+// DeleteBlogById deletes the blog only if it is still at blogVersion
+func (s *Storage) DeleteBlogById(blogId int, blogVersion int) error {
 
-	query := `DELETE FROM blogs WHERE id=$1`
+	query := `DELETE FROM blogs WHERE id=$1 AND blog_version=$2`
 
-	result, err := s.db.Exec(query, blogId)
+	result, err := s.db.Exec(query, blogId, blogVersion)
 	if err != nil {
 		return err
 	}
@@ -186,7 +257,7 @@ func (s *Storage) DeleteBlogById(blogId int) error {
 	}
 
 	if rowsAffected != 1 {
-		return errors.New("blog not found")
+		return ErrBlogVersionConflict
 	}
 
 	return nil
@@ -218,7 +289,7 @@ func (s *Storage) GetBlogTopics(blogId int) ([]Topic, error) {
 }
 
 // update blog status from 'draft' to 'published' and add additional topics to blog
-func (s *Storage) PublishBlogAndAddTopics(blogId int, topicIds []int) (*BlogWithUserAndTopics, error) {
+func (s *Storage) PublishBlogAndAddTopics(blogId int, blogVersion int, topicIds []int) (*BlogWithUserAndTopics, error) {
 
 	var updatedBlog BlogWithUserAndTopics
 
@@ -235,13 +306,16 @@ func (s *Storage) PublishBlogAndAddTopics(blogId int, topicIds []int) (*BlogWith
 
 	var blog Blog
 	// update blog status to 'published' query
-	updateBlogStatusQuery := `UPDATE blogs SET blog_status=$1,published_at=$2 WHERE id=$3 
+	updateBlogStatusQuery := `UPDATE blogs SET blog_status=$1,published_at=$2,publish_at=NULL,blog_version=blog_version+1 WHERE id=$3 AND blog_version=$4 
 RETURNING id,blog_title,blog_description,blog_content,blog_thumbnail,blog_status,blog_author_id,published_at,
-blog_created_at,blog_updated_at`
+blog_created_at,blog_updated_at,blog_version,publish_at`
 	//	add topics to blog
 
-	if err := tx.QueryRowx(updateBlogStatusQuery, BlogStatusPublished, time.Now(), blogId).StructScan(&blog); err != nil {
+	if err := tx.QueryRowx(updateBlogStatusQuery, BlogStatusPublished, time.Now(), blogId, blogVersion).StructScan(&blog); err != nil {
 		rollBackErr = err
+		if errors.Is(err, sql.ErrNoRows) {
+			rollBackErr = ErrBlogVersionConflict
+		}
 		return nil, rollBackErr
 	}
 
@@ -300,14 +374,139 @@ blog_created_at,blog_updated_at`
 	return &updatedBlog, nil
 }
 
-func (s *Storage) UpdateBlogStatus(blogId int, blogStatus BlogStatus) (*BlogWithUserAndTopics, error) {
+// ScheduleBlogAndAddTopics schedule a draft (or reschedule a scheduled blog) to be published at publishAt,
+// additional topics are added right away so the blog is ready to be published by the scheduler
+func (s *Storage) ScheduleBlogAndAddTopics(blogId int, blogVersion int, publishAt time.Time, topicIds []int) (*BlogWithUserAndTopics, error) {
+
+	var scheduledBlog BlogWithUserAndTopics
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var blog Blog
+	scheduleBlogQuery := `UPDATE blogs SET blog_status=$1,publish_at=$2,blog_version=blog_version+1 WHERE id=$3 AND blog_version=$4 
+	RETURNING id,blog_title,blog_description,blog_content,blog_thumbnail,blog_status,blog_author_id,published_at,
+	blog_created_at,blog_updated_at,blog_version,publish_at`
+
+	if err := tx.QueryRowx(scheduleBlogQuery, BlogStatusScheduled, publishAt, blogId, blogVersion).StructScan(&blog); err != nil {
+		rollBackErr = err
+		if errors.Is(err, sql.ErrNoRows) {
+			rollBackErr = ErrBlogVersionConflict
+		}
+		return nil, rollBackErr
+	}
+
+	insertTopicQuery := `INSERT INTO blog_topics(blog_id,topic_id) VALUES($1,$2)`
+
+	for _, topicId := range topicIds {
+		if _, rollBackErr = tx.Exec(insertTopicQuery, blog.Id, topicId); rollBackErr != nil {
+			return nil, rollBackErr
+		}
+	}
+
+	var topics []Topic
+	topicsQuery := `SELECT id,topic_name,created_at,updated_at 
+	FROM topics WHERE id IN (SELECT topic_id FROM blog_topics WHERE blog_id=$1)`
+
+	if rollBackErr = tx.Select(&topics, topicsQuery, blog.Id); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	var blogAuthor User
+	blogAuthorQuery := `SELECT id,email,username,password,name,profile_img,
+    is_verified,role,created_at,updated_at FROM users WHERE id=$1`
+
+	if rollBackErr = tx.QueryRowx(blogAuthorQuery, blog.BlogAuthorId).StructScan(&blogAuthor); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	scheduledBlog.Blog = blog
+	scheduledBlog.BlogTopics = topics
+	scheduledBlog.BlogAuthor = blogAuthor
+
+	return &scheduledBlog, nil
+}
+
+// PublishDueScheduledBlogs publishes every scheduled blog whose publish_at has passed (and that still has topics).
+// A single UPDATE flips the rows so concurrent schedulers never publish the same blog twice.
+func (s *Storage) PublishDueScheduledBlogs(now time.Time) ([]Blog, error) {
+
+	var blogs []Blog
+
+	query := `UPDATE blogs SET blog_status=$1,published_at=publish_at,publish_at=NULL,blog_version=blog_version+1 
+	WHERE blog_status=$2 AND publish_at <= $3 AND EXISTS (SELECT 1 FROM blog_topics WHERE blog_id=blogs.id)
+	RETURNING id,blog_title,blog_description,blog_content,blog_thumbnail,blog_status,blog_author_id,published_at,
+	blog_created_at,blog_updated_at,blog_version,publish_at`
+
+	rows, err := s.db.Queryx(query, BlogStatusPublished, BlogStatusScheduled, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var blog Blog
+
+		if err := rows.StructScan(&blog); err != nil {
+			return nil, err
+		}
+
+		blogs = append(blogs, blog)
+	}
+
+	return blogs, rows.Err()
+}
+
+// RevertUnpublishableScheduledBlogs due scheduled blogs that lost all their topics (topics deleted after scheduling)
+// cannot be published, they go back to being drafts
+func (s *Storage) RevertUnpublishableScheduledBlogs(now time.Time) ([]Blog, error) {
+
+	var blogs []Blog
+
+	query := `UPDATE blogs SET blog_status=$1,publish_at=NULL,blog_version=blog_version+1 
+	WHERE blog_status=$2 AND publish_at <= $3 AND NOT EXISTS (SELECT 1 FROM blog_topics WHERE blog_id=blogs.id)
+	RETURNING id,blog_title,blog_description,blog_content,blog_thumbnail,blog_status,blog_author_id,published_at,
+	blog_created_at,blog_updated_at,blog_version,publish_at`
+
+	rows, err := s.db.Queryx(query, BlogStatusDraft, BlogStatusScheduled, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var blog Blog
+
+		if err := rows.StructScan(&blog); err != nil {
+			return nil, err
+		}
+
+		blogs = append(blogs, blog)
+	}
+
+	return blogs, rows.Err()
+}
+
+func (s *Storage) UpdateBlogStatus(blogId int, blogVersion int, blogStatus BlogStatus) (*BlogWithUserAndTopics, error) {
 
 	var updatedBlog BlogWithUserAndTopics
 
 	var blog Blog
-	query := `UPDATE blogs SET blog_status=$1,published_at=$2 WHERE id=$3 
+	query := `UPDATE blogs SET blog_status=$1,published_at=$2,publish_at=NULL,blog_version=blog_version+1 WHERE id=$3 AND blog_version=$4 
 	RETURNING id,blog_title,blog_description,blog_content,blog_thumbnail,blog_status,blog_author_id,published_at,
-	blog_created_at,blog_updated_at`
+	blog_created_at,blog_updated_at,blog_version,publish_at`
 
 	var publishedAtArg any
 	if blogStatus == BlogStatusPublished {
@@ -316,7 +515,10 @@ func (s *Storage) UpdateBlogStatus(blogId int, blogStatus BlogStatus) (*BlogWith
 		publishedAtArg = nil
 	}
 
-	if err := s.db.QueryRowx(query, blogStatus, publishedAtArg, blogId).StructScan(&blog); err != nil {
+	if err := s.db.QueryRowx(query, blogStatus, publishedAtArg, blogId, blogVersion).StructScan(&blog); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrBlogVersionConflict
+		}
 		return nil, err
 	}
 
@@ -355,6 +557,103 @@ func (s *Storage) UpdateBlogStatus(blogId int, blogStatus BlogStatus) (*BlogWith
 	return &updatedBlog, nil
 }
 
+// UpdateBlog replaces the blog's editable fields and topics, every save is recorded as a blog revision
+// (the first edit also records the blog as it was before being edited)
+func (s *Storage) UpdateBlog(blogId int, blogVersion int, blogTitle string, blogDescription *string, blogContent json.RawMessage, blogThumbnail *string, topicIds []int) (*BlogWithUserAndTopics, error) {
+
+	var updatedBlog BlogWithUserAndTopics
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// lock the blog row so concurrent saves get consecutive revision numbers
+	var currentBlogVersion int
+	lockBlogQuery := `SELECT blog_version FROM blogs WHERE id=$1 FOR UPDATE`
+	if rollBackErr = tx.QueryRowx(lockBlogQuery, blogId).Scan(&currentBlogVersion); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if currentBlogVersion != blogVersion {
+		rollBackErr = ErrBlogVersionConflict
+		return nil, rollBackErr
+	}
+
+	var revisionsCount int
+	revisionsCountQuery := `SELECT COUNT(id) FROM blog_revisions WHERE blog_id=$1`
+	if rollBackErr = tx.QueryRowx(revisionsCountQuery, blogId).Scan(&revisionsCount); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if revisionsCount == 0 {
+		if _, rollBackErr = createBlogRevision(tx, blogId); rollBackErr != nil {
+			return nil, rollBackErr
+		}
+	}
+
+	var blog Blog
+	updateBlogQuery := `UPDATE blogs SET blog_title=$1,blog_description=$2,blog_content=$3,blog_thumbnail=$4,blog_updated_at=$5,
+	blog_version=blog_version+1 WHERE id=$6 RETURNING id,blog_title,blog_description,blog_content,blog_thumbnail,blog_status,blog_author_id,published_at,
+	blog_created_at,blog_updated_at,blog_version,publish_at`
+
+	if rollBackErr = tx.QueryRowx(updateBlogQuery, blogTitle, blogDescription, blogContent, blogThumbnail, time.Now(), blogId).StructScan(&blog); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	removeTopicsQuery := `DELETE FROM blog_topics WHERE blog_id=$1`
+	if _, rollBackErr = tx.Exec(removeTopicsQuery, blog.Id); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	topicIdsArg := make(pq.Int64Array, 0, len(topicIds))
+	for _, topicId := range topicIds {
+		topicIdsArg = append(topicIdsArg, int64(topicId))
+	}
+
+	// topics deleted since (when restoring an old revision) are skipped
+	insertTopicsQuery := `INSERT INTO blog_topics(blog_id,topic_id) SELECT $1,id FROM topics WHERE id = ANY($2)`
+	if _, rollBackErr = tx.Exec(insertTopicsQuery, blog.Id, topicIdsArg); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if _, rollBackErr = createBlogRevision(tx, blog.Id); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	var topics []Topic
+	topicsQuery := `SELECT id,topic_name,created_at,updated_at 
+	FROM topics WHERE id IN (SELECT topic_id FROM blog_topics WHERE blog_id=$1)`
+
+	if rollBackErr = tx.Select(&topics, topicsQuery, blog.Id); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	var blogAuthor User
+	blogAuthorQuery := `SELECT id,email,username,password,name,profile_img,
+    is_verified,role,created_at,updated_at FROM users WHERE id=$1`
+
+	if rollBackErr = tx.QueryRowx(blogAuthorQuery, blog.BlogAuthorId).StructScan(&blogAuthor); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	updatedBlog.Blog = blog
+	updatedBlog.BlogTopics = topics
+	updatedBlog.BlogAuthor = blogAuthor
+
+	return &updatedBlog, nil
+}
+
 func (s *Storage) GetBlogsByTopic(topicId int, skip int, limit int) ([]BlogWithMetaData, error) {
 
 	var blogs []BlogWithMetaData
@@ -386,6 +685,7 @@ FROM
       b.published_at,
       b.blog_created_at,
       b.blog_updated_at,
+      b.blog_version,
       u.id,
       u.email,
       u.username,
@@ -408,6 +708,7 @@ FROM
       AND bc.parent_comment_id IS NULL
     WHERE
       b.id IN (SELECT blog_id FROM blog_topics WHERE topic_id = $1) AND b.blog_status = 'published'
+      AND b.blog_author_id NOT IN (SELECT id FROM suspended_users)
     GROUP BY b.id,u.id
   )
 ORDER BY
@@ -426,7 +727,7 @@ LIMIT $2 OFFSET $3`
 		var activityScore float64
 
 		if err := rows.Scan(&blog.Id, &blog.BlogTitle, &blog.BlogDescription, &blog.BlogContent,
-			&blog.BlogThumbnail, &blog.BlogStatus, &blog.BlogAuthorId, &blog.PublishedAt, &blog.BlogCreatedAt, &blog.BlogUpdatedAt,
+			&blog.BlogThumbnail, &blog.BlogStatus, &blog.BlogAuthorId, &blog.PublishedAt, &blog.BlogCreatedAt, &blog.BlogUpdatedAt, &blog.BlogVersion,
 			&blog.BlogAuthor.Id, &blog.BlogAuthor.Email, &blog.BlogAuthor.Username, &blog.BlogAuthor.Password,
 			&blog.BlogAuthor.Name, &blog.BlogAuthor.ProfileImg, &blog.BlogAuthor.IsVerified, &blog.BlogAuthor.Role,
 			&blog.BlogAuthor.CreatedAt, &blog.BlogAuthor.UpdatedAt, &blog.BlogLikesCount, &blog.BlogBookmarksCount, &blog.BlogCommentsCount, &activityScore); err != nil {
@@ -463,7 +764,8 @@ func (s *Storage) GetBlogsByTopicCount(topicId int) (int, error) {
 
 	var totalBlogsCount int
 
-	query := `SELECT COUNT(id) FROM blogs WHERE id IN (SELECT blog_id FROM blog_topics WHERE topic_id=$1) AND blog_status='published'`
+	query := `SELECT COUNT(id) FROM blogs WHERE id IN (SELECT blog_id FROM blog_topics WHERE topic_id=$1) AND blog_status='published'
+	AND blog_author_id NOT IN (SELECT id FROM suspended_users)`
 
 	if err := s.db.QueryRowx(query, topicId).Scan(&totalBlogsCount); err != nil {
 		return -1, err
@@ -472,6 +774,120 @@ func (s *Storage) GetBlogsByTopicCount(topicId int) (int, error) {
 	return totalBlogsCount, nil
 }
 
+// GetBlogsByAuthor - an author's blogs with any of the given statuses, newest first (paginated)
+// excludeSuspendedAuthor returns no blogs while the author is suspended or banned (for everyone but the author)
+func (s *Storage) GetBlogsByAuthor(authorId int, blogStatuses []BlogStatus, excludeSuspendedAuthor bool, skip int, limit int) ([]BlogWithMetaData, error) {
+
+	var blogs []BlogWithMetaData
+
+	suspendedAuthorPredicate := ""
+	if excludeSuspendedAuthor {
+		suspendedAuthorPredicate = " AND b.blog_author_id NOT IN (SELECT id FROM suspended_users)"
+	}
+
+	query := `SELECT
+  b.id,
+  b.blog_title,
+  b.blog_description,
+  b.blog_content,
+  b.blog_thumbnail,
+  b.blog_status,
+  b.blog_author_id,
+  b.published_at,
+  b.publish_at,
+  b.blog_created_at,
+  b.blog_updated_at,
+  b.blog_version,
+  u.id,
+  u.email,
+  u.username,
+  u.password,
+  u.name,
+  u.profile_img,
+  u.is_verified,
+  u.role,
+  u.created_at,
+  u.updated_at,
+  COUNT(DISTINCT bl.liked_by_id) AS blog_likes_count,
+  COUNT(DISTINCT bb.bookmarked_by_id) AS blog_bookmarks_count,
+  COUNT(DISTINCT bc.id) AS blog_comments_count
+FROM
+  blogs AS b
+  INNER JOIN users AS u ON b.blog_author_id = u.id
+  LEFT JOIN blog_likes AS bl ON b.id = bl.liked_blog_id
+  LEFT JOIN blog_bookmarks AS bb ON b.id = bb.bookmarked_blog_id
+  LEFT JOIN blog_comments AS bc ON b.id = bc.blog_id
+  AND bc.parent_comment_id IS NULL
+WHERE
+  b.blog_author_id = $1 AND b.blog_status::text = ANY($2)` + suspendedAuthorPredicate + `
+GROUP BY b.id,u.id
+ORDER BY
+  COALESCE(b.published_at, b.blog_created_at) DESC
+LIMIT $3 OFFSET $4`
+
+	rows, err := s.db.Queryx(query, authorId, blogStatusesArray(blogStatuses), limit, skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var blog BlogWithMetaData
+
+		if err := rows.Scan(&blog.Id, &blog.BlogTitle, &blog.BlogDescription, &blog.BlogContent,
+			&blog.BlogThumbnail, &blog.BlogStatus, &blog.BlogAuthorId, &blog.PublishedAt, &blog.PublishAt, &blog.BlogCreatedAt, &blog.BlogUpdatedAt, &blog.BlogVersion,
+			&blog.BlogAuthor.Id, &blog.BlogAuthor.Email, &blog.BlogAuthor.Username, &blog.BlogAuthor.Password,
+			&blog.BlogAuthor.Name, &blog.BlogAuthor.ProfileImg, &blog.BlogAuthor.IsVerified, &blog.BlogAuthor.Role,
+			&blog.BlogAuthor.CreatedAt, &blog.BlogAuthor.UpdatedAt, &blog.BlogLikesCount, &blog.BlogBookmarksCount, &blog.BlogCommentsCount); err != nil {
+			return nil, err
+		}
+
+		blogs = append(blogs, blog)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// each blog can have multiple topics
+	for i := range blogs {
+		topics, err := s.GetBlogTopics(blogs[i].Id)
+		if err != nil {
+			return nil, err
+		}
+		blogs[i].BlogTopics = topics
+	}
+
+	return blogs, nil
+}
+
+func (s *Storage) GetBlogsByAuthorCount(authorId int, blogStatuses []BlogStatus, excludeSuspendedAuthor bool) (int, error) {
+
+	var totalBlogsCount int
+
+	query := `SELECT COUNT(id) FROM blogs WHERE blog_author_id=$1 AND blog_status::text = ANY($2)`
+	if excludeSuspendedAuthor {
+		query += ` AND blog_author_id NOT IN (SELECT id FROM suspended_users)`
+	}
+
+	if err := s.db.QueryRowx(query, authorId, blogStatusesArray(blogStatuses)).Scan(&totalBlogsCount); err != nil {
+		return -1, err
+	}
+
+	return totalBlogsCount, nil
+}
+
+func blogStatusesArray(blogStatuses []BlogStatus) pq.StringArray {
+
+	statuses := make(pq.StringArray, 0, len(blogStatuses))
+	for _, blogStatus := range blogStatuses {
+		statuses = append(statuses, string(blogStatus))
+	}
+
+	return statuses
+}
+
 // GetBlogsByTopNFollowedTopics - get blogs by  the top n followed topics (paginated)
 func (s *Storage) GetBlogsByTopNFollowedTopics(n int, skip int, limit int) ([]BlogWithMetaData, error) {
 
@@ -504,6 +920,7 @@ FROM
       b.published_at,
       b.blog_created_at,
       b.blog_updated_at,
+      b.blog_version,
       u.id,
       u.email,
       u.username,
@@ -550,6 +967,7 @@ FROM
               )
           )
       ) AND b.blog_status = 'published'
+      AND b.blog_author_id NOT IN (SELECT id FROM suspended_users)
     GROUP BY b.id, u.id
   )
 ORDER BY activity_score DESC
@@ -567,7 +985,7 @@ LIMIT $2 OFFSET $3`
 		var activityScore float64
 
 		if err := rows.Scan(&blog.Id, &blog.BlogTitle, &blog.BlogDescription, &blog.BlogContent,
-			&blog.BlogThumbnail, &blog.BlogStatus, &blog.BlogAuthorId, &blog.PublishedAt, &blog.BlogCreatedAt, &blog.BlogUpdatedAt,
+			&blog.BlogThumbnail, &blog.BlogStatus, &blog.BlogAuthorId, &blog.PublishedAt, &blog.BlogCreatedAt, &blog.BlogUpdatedAt, &blog.BlogVersion,
 			&blog.BlogAuthor.Id, &blog.BlogAuthor.Email, &blog.BlogAuthor.Username, &blog.BlogAuthor.Password,
 			&blog.BlogAuthor.Name, &blog.BlogAuthor.ProfileImg, &blog.BlogAuthor.IsVerified, &blog.BlogAuthor.Role,
 			&blog.BlogAuthor.CreatedAt, &blog.BlogAuthor.UpdatedAt, &blog.BlogLikesCount, &blog.BlogBookmarksCount, &blog.BlogCommentsCount, &activityScore); err != nil {
@@ -621,6 +1039,7 @@ func (s *Storage) GetBlogsByTopNFollowedTopicsCount(n int) (int, error) {
           $1
       )
   )) AND blog_status = 'published'
+  AND blog_author_id NOT IN (SELECT id FROM suspended_users)
 `
 	if err := s.db.QueryRowx(query, n).Scan(&totalBlogsCount); err != nil {
 		return -1, err
@@ -629,7 +1048,43 @@ func (s *Storage) GetBlogsByTopNFollowedTopicsCount(n int) (int, error) {
 	return totalBlogsCount, nil
 }
 
+// predicates of the personalized feeds, $1 is the user the feed is for
+const (
+	followedTopicsFeedPredicate = `b.id IN (SELECT DISTINCT(blog_id) FROM blog_topics
+	WHERE topic_id IN (SELECT topic_id FROM topic_follows WHERE user_id = $1))`
+	followedAuthorsFeedPredicate          = `b.blog_author_id IN (SELECT following_id FROM follows WHERE follower_id = $1)`
+	followedTopicsAndAuthorsFeedPredicate = `(` + followedTopicsFeedPredicate + ` OR ` + followedAuthorsFeedPredicate + `)`
+)
+
 func (s *Storage) GetBlogsByUserFollowedTopics(userId int, skip int, limit int) ([]BlogWithMetaData, error) {
+	return s.getUserFeedBlogs(followedTopicsFeedPredicate, userId, skip, limit)
+}
+
+func (s *Storage) GetBlogsByUserFollowedTopicsCount(userId int) (int, error) {
+	return s.getUserFeedBlogsCount(followedTopicsFeedPredicate, userId)
+}
+
+// GetBlogsByUserFollowedAuthors - published blogs written by authors the user follows (paginated)
+func (s *Storage) GetBlogsByUserFollowedAuthors(userId int, skip int, limit int) ([]BlogWithMetaData, error) {
+	return s.getUserFeedBlogs(followedAuthorsFeedPredicate, userId, skip, limit)
+}
+
+func (s *Storage) GetBlogsByUserFollowedAuthorsCount(userId int) (int, error) {
+	return s.getUserFeedBlogsCount(followedAuthorsFeedPredicate, userId)
+}
+
+// GetBlogsByUserFollowedTopicsAndAuthors - blogs from followed topics or followed authors, a blog matching both is returned once
+func (s *Storage) GetBlogsByUserFollowedTopicsAndAuthors(userId int, skip int, limit int) ([]BlogWithMetaData, error) {
+	return s.getUserFeedBlogs(followedTopicsAndAuthorsFeedPredicate, userId, skip, limit)
+}
+
+func (s *Storage) GetBlogsByUserFollowedTopicsAndAuthorsCount(userId int) (int, error) {
+	return s.getUserFeedBlogsCount(followedTopicsAndAuthorsFeedPredicate, userId)
+}
+
+// getUserFeedBlogs published blogs of non suspended authors matching feedPredicate (on blogs AS b, $1 is userId)
+// ordered by activity score
+func (s *Storage) getUserFeedBlogs(feedPredicate string, userId int, skip int, limit int) ([]BlogWithMetaData, error) {
 
 	var blogs []BlogWithMetaData
 
@@ -660,6 +1115,7 @@ FROM
       b.published_at,
       b.blog_created_at,
       b.blog_updated_at,
+      b.blog_version,
       u.id,
       u.email,
       u.username,
@@ -681,21 +1137,8 @@ FROM
       LEFT JOIN blog_comments AS bc ON b.id = bc.blog_id
       AND bc.parent_comment_id IS NULL
     WHERE
-      b.id IN (
-        SELECT DISTINCT
-          (blog_id)
-        FROM
-          blog_topics
-        WHERE
-          topic_id IN (
-            SELECT
-              topic_id
-            FROM
-              topic_follows
-            WHERE
-              user_id = $1
-          )
-      ) AND b.blog_status = 'published'
+      ` + feedPredicate + ` AND b.blog_status = 'published'
+      AND b.blog_author_id NOT IN (SELECT id FROM suspended_users)
     GROUP BY
       b.id,
       u.id
@@ -719,7 +1162,7 @@ OFFSET
 		var activityScore float64
 
 		if err := rows.Scan(&blog.Id, &blog.BlogTitle, &blog.BlogDescription, &blog.BlogContent,
-			&blog.BlogThumbnail, &blog.BlogStatus, &blog.BlogAuthorId, &blog.PublishedAt, &blog.BlogCreatedAt, &blog.BlogUpdatedAt,
+			&blog.BlogThumbnail, &blog.BlogStatus, &blog.BlogAuthorId, &blog.PublishedAt, &blog.BlogCreatedAt, &blog.BlogUpdatedAt, &blog.BlogVersion,
 			&blog.BlogAuthor.Id, &blog.BlogAuthor.Email, &blog.BlogAuthor.Username, &blog.BlogAuthor.Password,
 			&blog.BlogAuthor.Name, &blog.BlogAuthor.ProfileImg, &blog.BlogAuthor.IsVerified, &blog.BlogAuthor.Role,
 			&blog.BlogAuthor.CreatedAt, &blog.BlogAuthor.UpdatedAt, &blog.BlogLikesCount, &blog.BlogBookmarksCount, &blog.BlogCommentsCount, &activityScore); err != nil {
@@ -753,13 +1196,12 @@ OFFSET
 	return blogs, nil
 }
 
-func (s *Storage) GetBlogsByUserFollowedTopicsCount(userId int) (int, error) {
+func (s *Storage) getUserFeedBlogsCount(feedPredicate string, userId int) (int, error) {
 
 	var totalBlogsCount int
 
-	query := `SELECT COUNT(id) FROM blogs WHERE id IN (SELECT DISTINCT(blog_id)
-	FROM blog_topics WHERE topic_id IN (SELECT topic_id FROM topic_follows WHERE user_id=$1)) AND blog_status='published';
-	`
+	query := `SELECT COUNT(b.id) FROM blogs AS b WHERE ` + feedPredicate + ` AND b.blog_status='published'
+	AND b.blog_author_id NOT IN (SELECT id FROM suspended_users)`
 
 	if err := s.db.QueryRowx(query, userId).Scan(&totalBlogsCount); err != nil {
 		return -1, err
@@ -6,12 +6,19 @@ type BlogBookmark struct {
 	BookmarkedById   int    `db:"bookmarked_by_id" json:"bookmarked_by_id"`
 	BookmarkedBlogId int    `db:"bookmarked_blog_id" json:"bookmarked_blog_id"`
 	BookmarkedAt     string `db:"bookmarked_at" json:"bookmarked_at"`
+	BookmarkFolderId *int   `db:"bookmark_folder_id" json:"bookmark_folder_id"` // nil for unfiled bookmarks
+}
+
+type BookmarkedBlog struct {
+	BlogWithMetaData
+	BookmarkedAt     string `json:"bookmarked_at"`
+	BookmarkFolderId *int   `json:"bookmark_folder_id"`
 }
 
 func (s *Storage) GetBlogBookmark(bookmarkedById int, bookmarkedBlogId int) (*BlogBookmark, error) {
 	var blogBookmark BlogBookmark
 
-	query := `SELECT bookmarked_by_id, bookmarked_blog_id, bookmarked_at 
+	query := `SELECT bookmarked_by_id, bookmarked_blog_id, bookmarked_at, bookmark_folder_id
 	FROM blog_bookmarks WHERE bookmarked_by_id=$1 AND bookmarked_blog_id=$2`
 
 	if err := s.db.QueryRowx(query, bookmarkedById, bookmarkedBlogId).StructScan(&blogBookmark); err != nil {
@@ -26,7 +33,7 @@ func (s *Storage) CreateBlogBookmark(bookmarkedById int, bookmarkedBlogId int) (
 	var blogBookmark BlogBookmark
 
 	query := `INSERT INTO blog_bookmarks(bookmarked_by_id, bookmarked_blog_id) VALUES($1,$2) RETURNING 
-	bookmarked_by_id, bookmarked_blog_id,bookmarked_at`
+	bookmarked_by_id, bookmarked_blog_id,bookmarked_at,bookmark_folder_id`
 
 	if err := s.db.QueryRowx(query, bookmarkedById, bookmarkedBlogId).StructScan(&blogBookmark); err != nil {
 		return nil, err
@@ -52,6 +59,122 @@ func (s *Storage) RemoveBlogBookmark(bookmarkedById int, bookmarkedBlogId int) e
 	if rowsAffected != 1 {
 		return errors.New("blog bookmark not deleted")
 	}
-	
+
 	return nil
 }
+
+// UpdateBlogBookmarkFolder moves a bookmark into bookmarkFolderId (nil to unfile it)
+func (s *Storage) UpdateBlogBookmarkFolder(bookmarkedById int, bookmarkedBlogId int, bookmarkFolderId *int) (*BlogBookmark, error) {
+
+	var blogBookmark BlogBookmark
+
+	query := `UPDATE blog_bookmarks SET bookmark_folder_id=$1 WHERE bookmarked_by_id=$2 AND bookmarked_blog_id=$3 
+	RETURNING bookmarked_by_id,bookmarked_blog_id,bookmarked_at,bookmark_folder_id`
+
+	if err := s.db.QueryRowx(query, bookmarkFolderId, bookmarkedById, bookmarkedBlogId).StructScan(&blogBookmark); err != nil {
+		return nil, err
+	}
+
+	return &blogBookmark, nil
+}
+
+// GetUserBookmarkedBlogs - blogs bookmarked by the user, most recently bookmarked first (paginated)
+// bookmarkFolderId optionally narrows the listing down to a single folder
+// blogs that are no longer published are left out unless the user is their author
+func (s *Storage) GetUserBookmarkedBlogs(userId int, bookmarkFolderId *int, skip int, limit int) ([]BookmarkedBlog, error) {
+
+	var blogs []BookmarkedBlog
+
+	query := `SELECT
+  b.id,
+  b.blog_title,
+  b.blog_description,
+  b.blog_content,
+  b.blog_thumbnail,
+  b.blog_status,
+  b.blog_author_id,
+  b.published_at,
+  b.blog_created_at,
+  b.blog_updated_at,
+  b.blog_version,
+  u.id,
+  u.email,
+  u.username,
+  u.password,
+  u.name,
+  u.profile_img,
+  u.is_verified,
+  u.role,
+  u.created_at,
+  u.updated_at,
+  (SELECT COUNT(*) FROM blog_likes WHERE liked_blog_id = b.id) AS blog_likes_count,
+  (SELECT COUNT(*) FROM blog_bookmarks WHERE bookmarked_blog_id = b.id) AS blog_bookmarks_count,
+  (SELECT COUNT(*) FROM blog_comments WHERE blog_id = b.id AND parent_comment_id IS NULL) AS blog_comments_count,
+  bm.bookmarked_at,
+  bm.bookmark_folder_id
+FROM
+  blog_bookmarks AS bm
+  INNER JOIN blogs AS b ON bm.bookmarked_blog_id = b.id
+  INNER JOIN users AS u ON b.blog_author_id = u.id
+WHERE
+  bm.bookmarked_by_id = $1
+  AND (b.blog_status = 'published' OR b.blog_author_id = $1)
+  AND ($2::integer IS NULL OR bm.bookmark_folder_id = $2)
+ORDER BY
+  bm.bookmarked_at DESC
+LIMIT $3 OFFSET $4`
+
+	rows, err := s.db.Queryx(query, userId, bookmarkFolderId, limit, skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var blog BookmarkedBlog
+
+		if err := rows.Scan(&blog.Id, &blog.BlogTitle, &blog.BlogDescription, &blog.BlogContent,
+			&blog.BlogThumbnail, &blog.BlogStatus, &blog.BlogAuthorId, &blog.PublishedAt, &blog.BlogCreatedAt, &blog.BlogUpdatedAt, &blog.BlogVersion,
+			&blog.BlogAuthor.Id, &blog.BlogAuthor.Email, &blog.BlogAuthor.Username, &blog.BlogAuthor.Password,
+			&blog.BlogAuthor.Name, &blog.BlogAuthor.ProfileImg, &blog.BlogAuthor.IsVerified, &blog.BlogAuthor.Role,
+			&blog.BlogAuthor.CreatedAt, &blog.BlogAuthor.UpdatedAt, &blog.BlogLikesCount, &blog.BlogBookmarksCount, &blog.BlogCommentsCount,
+			&blog.BookmarkedAt, &blog.BookmarkFolderId); err != nil {
+			return nil, err
+		}
+
+		blogs = append(blogs, blog)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// each blog can have multiple topics
+	for i := range blogs {
+		topics, err := s.GetBlogTopics(blogs[i].Id)
+		if err != nil {
+			return nil, err
+		}
+		blogs[i].BlogTopics = topics
+	}
+
+	return blogs, nil
+}
+
+func (s *Storage) GetUserBookmarkedBlogsCount(userId int, bookmarkFolderId *int) (int, error) {
+
+	var totalCount int
+
+	query := `SELECT COUNT(bm.bookmarked_blog_id) FROM blog_bookmarks AS bm
+	INNER JOIN blogs AS b ON bm.bookmarked_blog_id = b.id
+	WHERE bm.bookmarked_by_id = $1
+	AND (b.blog_status = 'published' OR b.blog_author_id = $1)
+	AND ($2::integer IS NULL OR bm.bookmark_folder_id = $2)`
+
+	if err := s.db.QueryRowx(query, userId, bookmarkFolderId).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
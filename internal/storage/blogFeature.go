@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+type FeaturedBlog struct {
+	BlogWithMetaData
+	FeaturedAt string `json:"featured_at"`
+}
+
+// FeatureBlog features a published blog, featuring an already featured blog keeps its featured_at.
+// returns sql.ErrNoRows if the blog does not exist or is not published
+func (s *Storage) FeatureBlog(blogId int) error {
+
+	query := `UPDATE blogs SET featured_at=COALESCE(featured_at,$1) WHERE id=$2 AND blog_status='published'`
+
+	result, err := s.db.Exec(query, time.Now(), blogId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s *Storage) UnfeatureBlog(blogId int) error {
+
+	query := `UPDATE blogs SET featured_at=NULL WHERE id=$1`
+
+	_, err := s.db.Exec(query, blogId)
+	return err
+}
+
+// GetFeaturedBlogs - featured blogs that are still published, most recently featured first (paginated)
+func (s *Storage) GetFeaturedBlogs(skip int, limit int) ([]FeaturedBlog, error) {
+
+	var blogs []FeaturedBlog
+
+	query := `SELECT
+  b.id,
+  b.blog_title,
+  b.blog_description,
+  b.blog_content,
+  b.blog_thumbnail,
+  b.blog_status,
+  b.blog_author_id,
+  b.published_at,
+  b.blog_created_at,
+  b.blog_updated_at,
+  b.blog_version,
+  u.id,
+  u.email,
+  u.username,
+  u.password,
+  u.name,
+  u.profile_img,
+  u.is_verified,
+  u.role,
+  u.created_at,
+  u.updated_at,
+  (SELECT COUNT(*) FROM blog_likes WHERE liked_blog_id = b.id) AS blog_likes_count,
+  (SELECT COUNT(*) FROM blog_bookmarks WHERE bookmarked_blog_id = b.id) AS blog_bookmarks_count,
+  (SELECT COUNT(*) FROM blog_comments WHERE blog_id = b.id AND parent_comment_id IS NULL) AS blog_comments_count,
+  b.featured_at
+FROM
+  blogs AS b
+  INNER JOIN users AS u ON b.blog_author_id = u.id
+WHERE
+  b.featured_at IS NOT NULL
+  AND b.blog_status = 'published'
+  AND b.blog_author_id NOT IN (SELECT id FROM suspended_users)
+ORDER BY
+  b.featured_at DESC
+LIMIT $1 OFFSET $2`
+
+	rows, err := s.db.Queryx(query, limit, skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var blog FeaturedBlog
+
+		if err := rows.Scan(&blog.Id, &blog.BlogTitle, &blog.BlogDescription, &blog.BlogContent,
+			&blog.BlogThumbnail, &blog.BlogStatus, &blog.BlogAuthorId, &blog.PublishedAt, &blog.BlogCreatedAt, &blog.BlogUpdatedAt, &blog.BlogVersion,
+			&blog.BlogAuthor.Id, &blog.BlogAuthor.Email, &blog.BlogAuthor.Username, &blog.BlogAuthor.Password,
+			&blog.BlogAuthor.Name, &blog.BlogAuthor.ProfileImg, &blog.BlogAuthor.IsVerified, &blog.BlogAuthor.Role,
+			&blog.BlogAuthor.CreatedAt, &blog.BlogAuthor.UpdatedAt, &blog.BlogLikesCount, &blog.BlogBookmarksCount, &blog.BlogCommentsCount,
+			&blog.FeaturedAt); err != nil {
+			return nil, err
+		}
+
+		blogs = append(blogs, blog)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// each blog can have multiple topics
+	for i := range blogs {
+		topics, err := s.GetBlogTopics(blogs[i].Id)
+		if err != nil {
+			return nil, err
+		}
+		blogs[i].BlogTopics = topics
+	}
+
+	return blogs, nil
+}
+
+func (s *Storage) GetFeaturedBlogsCount() (int, error) {
+
+	var totalCount int
+
+	query := `SELECT COUNT(id) FROM blogs WHERE featured_at IS NOT NULL AND blog_status='published'
+	AND blog_author_id NOT IN (SELECT id FROM suspended_users)`
+
+	if err := s.db.QueryRowx(query).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
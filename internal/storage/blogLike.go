@@ -8,6 +8,11 @@ type BlogLike struct {
 	LikedAt     string `db:"liked_at" json:"liked_at"`
 }
 
+type LikedBlog struct {
+	BlogWithMetaData
+	LikedAt string `json:"liked_at"`
+}
+
 func (s *Storage) GetBlogLike(likedById int, likedBlogId int) (*BlogLike, error) {
 
 	var blogLike BlogLike
@@ -54,3 +59,100 @@ func (s *Storage) RemoveBlogLike(likedById int, likedBlogId int) error {
 
 	return nil
 }
+
+// GetUserLikedBlogs - blogs liked by the user, most recently liked first (paginated)
+// blogs that are no longer published are left out unless the user is their author
+func (s *Storage) GetUserLikedBlogs(userId int, skip int, limit int) ([]LikedBlog, error) {
+
+	var blogs []LikedBlog
+
+	query := `SELECT
+  b.id,
+  b.blog_title,
+  b.blog_description,
+  b.blog_content,
+  b.blog_thumbnail,
+  b.blog_status,
+  b.blog_author_id,
+  b.published_at,
+  b.blog_created_at,
+  b.blog_updated_at,
+  b.blog_version,
+  u.id,
+  u.email,
+  u.username,
+  u.password,
+  u.name,
+  u.profile_img,
+  u.is_verified,
+  u.role,
+  u.created_at,
+  u.updated_at,
+  (SELECT COUNT(*) FROM blog_likes WHERE liked_blog_id = b.id) AS blog_likes_count,
+  (SELECT COUNT(*) FROM blog_bookmarks WHERE bookmarked_blog_id = b.id) AS blog_bookmarks_count,
+  (SELECT COUNT(*) FROM blog_comments WHERE blog_id = b.id AND parent_comment_id IS NULL) AS blog_comments_count,
+  bl.liked_at
+FROM
+  blog_likes AS bl
+  INNER JOIN blogs AS b ON bl.liked_blog_id = b.id
+  INNER JOIN users AS u ON b.blog_author_id = u.id
+WHERE
+  bl.liked_by_id = $1
+  AND (b.blog_status = 'published' OR b.blog_author_id = $1)
+ORDER BY
+  bl.liked_at DESC
+LIMIT $2 OFFSET $3`
+
+	rows, err := s.db.Queryx(query, userId, limit, skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var blog LikedBlog
+
+		if err := rows.Scan(&blog.Id, &blog.BlogTitle, &blog.BlogDescription, &blog.BlogContent,
+			&blog.BlogThumbnail, &blog.BlogStatus, &blog.BlogAuthorId, &blog.PublishedAt, &blog.BlogCreatedAt, &blog.BlogUpdatedAt, &blog.BlogVersion,
+			&blog.BlogAuthor.Id, &blog.BlogAuthor.Email, &blog.BlogAuthor.Username, &blog.BlogAuthor.Password,
+			&blog.BlogAuthor.Name, &blog.BlogAuthor.ProfileImg, &blog.BlogAuthor.IsVerified, &blog.BlogAuthor.Role,
+			&blog.BlogAuthor.CreatedAt, &blog.BlogAuthor.UpdatedAt, &blog.BlogLikesCount, &blog.BlogBookmarksCount, &blog.BlogCommentsCount,
+			&blog.LikedAt); err != nil {
+			return nil, err
+		}
+
+		blogs = append(blogs, blog)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// each blog can have multiple topics
+	for i := range blogs {
+		topics, err := s.GetBlogTopics(blogs[i].Id)
+		if err != nil {
+			return nil, err
+		}
+		blogs[i].BlogTopics = topics
+	}
+
+	return blogs, nil
+}
+
+func (s *Storage) GetUserLikedBlogsCount(userId int) (int, error) {
+
+	var totalCount int
+
+	query := `SELECT COUNT(bl.liked_blog_id) FROM blog_likes AS bl
+	INNER JOIN blogs AS b ON bl.liked_blog_id = b.id
+	WHERE bl.liked_by_id = $1
+	AND (b.blog_status = 'published' OR b.blog_author_id = $1)`
+
+	if err := s.db.QueryRowx(query, userId).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"encoding/json"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// BlogRevision immutable snapshot of a blog's editable fields, written on every blog edit
+type BlogRevision struct {
+	Id                int             `db:"id" json:"id"`
+	BlogId            int             `db:"blog_id" json:"blog_id"`
+	RevisionNumber    int             `db:"revision_number" json:"revision_number"`
+	BlogTitle         string          `db:"blog_title" json:"blog_title"`
+	BlogDescription   *string         `db:"blog_description" json:"blog_description"`
+	BlogContent       json.RawMessage `db:"blog_content" json:"blog_content"`
+	BlogThumbnail     *string         `db:"blog_thumbnail" json:"blog_thumbnail"`
+	BlogTopicIds      pq.Int64Array   `db:"blog_topic_ids" json:"blog_topic_ids"`
+	RevisionCreatedAt string          `db:"revision_created_at" json:"revision_created_at"`
+}
+
+// createBlogRevision snapshots the blog's current row and topics (inside the caller's transaction)
+func createBlogRevision(tx *sqlx.Tx, blogId int) (*BlogRevision, error) {
+
+	var blogRevision BlogRevision
+
+	query := `INSERT INTO blog_revisions(blog_id,revision_number,blog_title,blog_description,blog_content,blog_thumbnail,blog_topic_ids)
+	SELECT b.id,
+	(SELECT COALESCE(MAX(revision_number),0)+1 FROM blog_revisions WHERE blog_id=b.id),
+	b.blog_title,b.blog_description,b.blog_content,b.blog_thumbnail,
+	ARRAY(SELECT topic_id FROM blog_topics WHERE blog_id=b.id ORDER BY topic_id)
+	FROM blogs AS b WHERE b.id=$1
+	RETURNING id,blog_id,revision_number,blog_title,blog_description,blog_content,blog_thumbnail,blog_topic_ids,revision_created_at`
+
+	if err := tx.QueryRowx(query, blogId).StructScan(&blogRevision); err != nil {
+		return nil, err
+	}
+
+	return &blogRevision, nil
+}
+
+// GetBlogRevisions newest revision first
+func (s *Storage) GetBlogRevisions(blogId int, skip int, limit int) ([]BlogRevision, error) {
+
+	var blogRevisions []BlogRevision
+
+	query := `SELECT id,blog_id,revision_number,blog_title,blog_description,blog_content,blog_thumbnail,blog_topic_ids,revision_created_at
+	FROM blog_revisions WHERE blog_id=$1
+	ORDER BY revision_number DESC
+	LIMIT $2 OFFSET $3`
+
+	rows, err := s.db.Queryx(query, blogId, limit, skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var blogRevision BlogRevision
+
+		if err := rows.StructScan(&blogRevision); err != nil {
+			return nil, err
+		}
+
+		blogRevisions = append(blogRevisions, blogRevision)
+	}
+
+	return blogRevisions, nil
+}
+
+func (s *Storage) GetBlogRevisionsCount(blogId int) (int, error) {
+
+	var totalCount int
+
+	query := `SELECT COUNT(id) FROM blog_revisions WHERE blog_id=$1`
+
+	if err := s.db.QueryRowx(query, blogId).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
+
+func (s *Storage) GetBlogRevision(blogId int, revisionNumber int) (*BlogRevision, error) {
+
+	var blogRevision BlogRevision
+
+	query := `SELECT id,blog_id,revision_number,blog_title,blog_description,blog_content,blog_thumbnail,blog_topic_ids,revision_created_at
+	FROM blog_revisions WHERE blog_id=$1 AND revision_number=$2`
+
+	if err := s.db.QueryRowx(query, blogId, revisionNumber).StructScan(&blogRevision); err != nil {
+		return nil, err
+	}
+
+	return &blogRevision, nil
+}
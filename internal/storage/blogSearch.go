@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"html"
+	"strings"
+)
+
+// full text search over published blogs, backed by the generated blogs.blog_search_vector column
+// (see migrations/000017_add_blogs_search_vector)
+
+// ts_headline marks matches with private use characters instead of <mark></mark>, the headline is
+// html escaped first (titles and content are author controlled) and only then are the markers turned into tags
+const (
+	blogSearchHighlightStart         = "\uE000"
+	blogSearchHighlightStop          = "\uE001"
+	blogSearchTitleHeadlineOptions   = `HighlightAll=true, StartSel="` + blogSearchHighlightStart + `", StopSel="` + blogSearchHighlightStop + `"`
+	blogSearchSnippetHeadlineOptions = `MaxFragments=2, MaxWords=35, MinWords=15, StartSel="` + blogSearchHighlightStart + `", StopSel="` + blogSearchHighlightStop + `"`
+)
+
+var blogSearchHighlightReplacer = strings.NewReplacer(blogSearchHighlightStart, "<mark>", blogSearchHighlightStop, "</mark>")
+
+// blogSearchHighlightHtml escapes a ts_headline result, the matched terms are the only markup in it
+func blogSearchHighlightHtml(headline string) string {
+	return blogSearchHighlightReplacer.Replace(html.EscapeString(headline))
+}
+
+type BlogSearchResult struct {
+	BlogWithMetaData
+	SearchRank     float64 `json:"search_rank"`
+	TitleHighlight string  `json:"title_highlight"` // html escaped blog title with matched terms wrapped in <mark></mark>
+	Snippet        string  `json:"snippet"`         // html escaped fragments of the description and content around the matched terms
+}
+
+// SearchBlogs ranks published blogs matching searchText (websearch syntax: "quoted phrases", or, -exclude),
+// topicId and authorId are optional filters
+func (s *Storage) SearchBlogs(searchText string, topicId *int, authorId *int, skip int, limit int) ([]BlogSearchResult, error) {
+
+	var blogs []BlogSearchResult
+
+	//	headlines are expensive, so they are only built for the page of blogs being returned
+	query := `WITH search_query AS (
+  SELECT websearch_to_tsquery('english', $1) AS query
+),
+matched_blogs AS (
+  SELECT
+    b.id,
+    ts_rank_cd(b.blog_search_vector, sq.query) AS search_rank
+  FROM
+    blogs AS b,
+    search_query AS sq
+  WHERE
+    b.blog_status = 'published'
+    AND b.blog_author_id NOT IN (SELECT id FROM suspended_users)
+    AND b.blog_search_vector @@ sq.query
+    AND ($2::integer IS NULL OR b.id IN (SELECT blog_id FROM blog_topics WHERE topic_id = $2))
+    AND ($3::integer IS NULL OR b.blog_author_id = $3)
+  ORDER BY
+    search_rank DESC,
+    b.published_at DESC
+  LIMIT $4 OFFSET $5
+)
+SELECT
+  b.id,
+  b.blog_title,
+  b.blog_description,
+  b.blog_content,
+  b.blog_thumbnail,
+  b.blog_status,
+  b.blog_author_id,
+  b.published_at,
+  b.blog_created_at,
+  b.blog_updated_at,
+  b.blog_version,
+  u.id,
+  u.email,
+  u.username,
+  u.password,
+  u.name,
+  u.profile_img,
+  u.is_verified,
+  u.role,
+  u.created_at,
+  u.updated_at,
+  (SELECT COUNT(*) FROM blog_likes WHERE liked_blog_id = b.id) AS blog_likes_count,
+  (SELECT COUNT(*) FROM blog_bookmarks WHERE bookmarked_blog_id = b.id) AS blog_bookmarks_count,
+  (SELECT COUNT(*) FROM blog_comments WHERE blog_id = b.id AND parent_comment_id IS NULL) AS blog_comments_count,
+  mb.search_rank,
+  ts_headline('english', b.blog_title, sq.query, $6),
+  ts_headline(
+    'english',
+    concat_ws(
+      ' ',
+      b.blog_description,
+      (SELECT string_agg(v #>> '{}', ' ') FROM jsonb_path_query(b.blog_content, 'strict $.** ? (@.type() == "string")') AS v)
+    ),
+    sq.query,
+    $7
+  )
+FROM
+  matched_blogs AS mb
+  INNER JOIN blogs AS b ON b.id = mb.id
+  INNER JOIN users AS u ON b.blog_author_id = u.id
+  CROSS JOIN search_query AS sq
+ORDER BY
+  mb.search_rank DESC,
+  b.published_at DESC`
+
+	rows, err := s.db.Queryx(query, searchText, topicId, authorId, limit, skip, blogSearchTitleHeadlineOptions, blogSearchSnippetHeadlineOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var blog BlogSearchResult
+
+		if err := rows.Scan(&blog.Id, &blog.BlogTitle, &blog.BlogDescription, &blog.BlogContent,
+			&blog.BlogThumbnail, &blog.BlogStatus, &blog.BlogAuthorId, &blog.PublishedAt, &blog.BlogCreatedAt, &blog.BlogUpdatedAt, &blog.BlogVersion,
+			&blog.BlogAuthor.Id, &blog.BlogAuthor.Email, &blog.BlogAuthor.Username, &blog.BlogAuthor.Password,
+			&blog.BlogAuthor.Name, &blog.BlogAuthor.ProfileImg, &blog.BlogAuthor.IsVerified, &blog.BlogAuthor.Role,
+			&blog.BlogAuthor.CreatedAt, &blog.BlogAuthor.UpdatedAt, &blog.BlogLikesCount, &blog.BlogBookmarksCount, &blog.BlogCommentsCount,
+			&blog.SearchRank, &blog.TitleHighlight, &blog.Snippet); err != nil {
+			return nil, err
+		}
+
+		blog.TitleHighlight = blogSearchHighlightHtml(blog.TitleHighlight)
+		blog.Snippet = blogSearchHighlightHtml(blog.Snippet)
+
+		blogs = append(blogs, blog)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// each blog can have multiple topics
+	for i := range blogs {
+		topics, err := s.GetBlogTopics(blogs[i].Id)
+		if err != nil {
+			return nil, err
+		}
+		blogs[i].BlogTopics = topics
+	}
+
+	return blogs, nil
+}
+
+func (s *Storage) SearchBlogsCount(searchText string, topicId *int, authorId *int) (int, error) {
+
+	var totalBlogsCount int
+
+	query := `SELECT COUNT(id) FROM blogs
+	WHERE blog_status = 'published'
+	AND blog_author_id NOT IN (SELECT id FROM suspended_users)
+	AND blog_search_vector @@ websearch_to_tsquery('english', $1)
+	AND ($2::integer IS NULL OR id IN (SELECT blog_id FROM blog_topics WHERE topic_id = $2))
+	AND ($3::integer IS NULL OR blog_author_id = $3)`
+
+	if err := s.db.QueryRowx(query, searchText, topicId, authorId).Scan(&totalBlogsCount); err != nil {
+		return -1, err
+	}
+
+	return totalBlogsCount, nil
+}
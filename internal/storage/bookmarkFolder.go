@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// BookmarkFolder user owned folder to organise bookmarks (reading list)
+type BookmarkFolder struct {
+	Id             int     `db:"id" json:"id"`
+	UserId         int     `db:"user_id" json:"user_id"`
+	FolderName     string  `db:"folder_name" json:"folder_name"`
+	CreatedAt      string  `db:"created_at" json:"created_at"`
+	UpdatedAt      *string `db:"updated_at" json:"updated_at"`
+	BookmarksCount int     `db:"bookmarks_count" json:"bookmarks_count"`
+}
+
+func (s *Storage) GetBookmarkFolderById(bookmarkFolderId int) (*BookmarkFolder, error) {
+
+	var bookmarkFolder BookmarkFolder
+
+	query := `SELECT id,user_id,folder_name,created_at,updated_at,
+	(SELECT COUNT(bookmarked_blog_id) FROM blog_bookmarks WHERE bookmark_folder_id=bookmark_folders.id) AS bookmarks_count
+	FROM bookmark_folders WHERE id=$1`
+
+	if err := s.db.QueryRowx(query, bookmarkFolderId).StructScan(&bookmarkFolder); err != nil {
+		return nil, err
+	}
+
+	return &bookmarkFolder, nil
+}
+
+func (s *Storage) GetBookmarkFolderByName(userId int, folderName string) (*BookmarkFolder, error) {
+
+	var bookmarkFolder BookmarkFolder
+
+	query := `SELECT id,user_id,folder_name,created_at,updated_at,
+	(SELECT COUNT(bookmarked_blog_id) FROM blog_bookmarks WHERE bookmark_folder_id=bookmark_folders.id) AS bookmarks_count
+	FROM bookmark_folders WHERE user_id=$1 AND folder_name=$2`
+
+	if err := s.db.QueryRowx(query, userId, folderName).StructScan(&bookmarkFolder); err != nil {
+		return nil, err
+	}
+
+	return &bookmarkFolder, nil
+}
+
+func (s *Storage) GetUserBookmarkFolders(userId int) ([]BookmarkFolder, error) {
+
+	var bookmarkFolders []BookmarkFolder
+
+	query := `SELECT id,user_id,folder_name,created_at,updated_at,
+	(SELECT COUNT(bookmarked_blog_id) FROM blog_bookmarks WHERE bookmark_folder_id=bookmark_folders.id) AS bookmarks_count
+	FROM bookmark_folders WHERE user_id=$1 ORDER BY folder_name`
+
+	rows, err := s.db.Queryx(query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookmarkFolder BookmarkFolder
+
+		if err := rows.StructScan(&bookmarkFolder); err != nil {
+			return nil, err
+		}
+
+		bookmarkFolders = append(bookmarkFolders, bookmarkFolder)
+	}
+
+	return bookmarkFolders, nil
+}
+
+func (s *Storage) CreateBookmarkFolder(userId int, folderName string) (*BookmarkFolder, error) {
+
+	var bookmarkFolder BookmarkFolder
+
+	query := `INSERT INTO bookmark_folders(user_id,folder_name) VALUES($1,$2) 
+	RETURNING id,user_id,folder_name,created_at,updated_at`
+
+	if err := s.db.QueryRowx(query, userId, folderName).StructScan(&bookmarkFolder); err != nil {
+		return nil, err
+	}
+
+	return &bookmarkFolder, nil
+}
+
+func (s *Storage) UpdateBookmarkFolderName(bookmarkFolderId int, folderName string) (*BookmarkFolder, error) {
+
+	var bookmarkFolder BookmarkFolder
+
+	query := `UPDATE bookmark_folders SET folder_name=$1,updated_at=$2 WHERE id=$3 
+	RETURNING id,user_id,folder_name,created_at,updated_at,
+	(SELECT COUNT(bookmarked_blog_id) FROM blog_bookmarks WHERE bookmark_folder_id=bookmark_folders.id) AS bookmarks_count`
+
+	if err := s.db.QueryRowx(query, folderName, time.Now(), bookmarkFolderId).StructScan(&bookmarkFolder); err != nil {
+		return nil, err
+	}
+
+	return &bookmarkFolder, nil
+}
+
+// DeleteBookmarkFolder the folder's bookmarks are kept as unfiled bookmarks (ON DELETE SET NULL)
+func (s *Storage) DeleteBookmarkFolder(bookmarkFolderId int) error {
+
+	query := `DELETE FROM bookmark_folders WHERE id=$1`
+
+	result, err := s.db.Exec(query, bookmarkFolderId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return errors.New("bookmark folder not deleted")
+	}
+
+	return nil
+}
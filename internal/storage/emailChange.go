@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+type EmailChange struct {
+	Token        string `db:"token" json:"token"`
+	UserId       int    `db:"user_id" json:"user_id"`
+	NewEmail     string `db:"new_email" json:"new_email"`
+	ExpirationAt string `db:"expiration_at" json:"expiration_at"`
+	CreatedAt    string `db:"created_at" json:"created_at"`
+}
+
+// ErrEmailTaken another verified user already owns the email
+var ErrEmailTaken = errors.New("email taken")
+
+// CreateEmailChange removes any pending email change of the user so that only the latest confirmation link works
+func (s *Storage) CreateEmailChange(userId int, token string, newEmail string, expirationAt time.Time) (*EmailChange, error) {
+
+	var emailChange EmailChange
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	cleanUpQuery := `DELETE FROM email_changes WHERE user_id=$1`
+	if _, rollBackErr = tx.Exec(cleanUpQuery, userId); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	query := `INSERT INTO email_changes(token,user_id,new_email,expiration_at) VALUES($1,$2,$3,$4)
+	RETURNING token,user_id,new_email,expiration_at,created_at`
+
+	if rollBackErr = tx.QueryRowx(query, token, userId, newEmail, expirationAt).StructScan(&emailChange); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	return &emailChange, nil
+}
+
+// ConfirmEmailChange applies the pending email change of the token, returns sql.ErrNoRows if the token
+// is invalid or expired and ErrEmailTaken if another verified user took the email in the meantime.
+// links sent to the old address (password resets, sign-in links) stop working and pending
+// registrations for the new address are removed, its owner has just proven control of it
+func (s *Storage) ConfirmEmailChange(token string) (*User, error) {
+
+	var emailChange EmailChange
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `SELECT token,user_id,new_email,expiration_at,created_at FROM email_changes 
+	WHERE token=$1 AND expiration_at > $2 FOR UPDATE`
+
+	if rollBackErr = tx.QueryRowx(query, token, time.Now()).StructScan(&emailChange); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	var isEmailTaken bool
+
+	emailTakenQuery := `SELECT EXISTS(SELECT 1 FROM users WHERE email=$1 AND is_verified=true AND is_placeholder=false AND id != $2)`
+	if rollBackErr = tx.QueryRowx(emailTakenQuery, emailChange.NewEmail, emailChange.UserId).Scan(&isEmailTaken); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if isEmailTaken {
+		rollBackErr = ErrEmailTaken
+		return nil, rollBackErr
+	}
+
+	var user User
+
+	updateUserQuery := `UPDATE users SET email=$1,updated_at=$2 WHERE id=$3 RETURNING 
+	id,email,username,password,name,profile_img,is_verified,role,created_at,updated_at`
+
+	if rollBackErr = tx.QueryRowx(updateUserQuery, emailChange.NewEmail, time.Now(), emailChange.UserId).StructScan(&user); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	cleanUpQueries := []string{
+		`DELETE FROM email_changes WHERE user_id=$1`,
+		`DELETE FROM password_resets WHERE user_id=$1`,
+		`DELETE FROM magic_links WHERE user_id=$1`,
+	}
+	for _, cleanUpQuery := range cleanUpQueries {
+		if _, rollBackErr = tx.Exec(cleanUpQuery, user.Id); rollBackErr != nil {
+			return nil, rollBackErr
+		}
+	}
+
+	cleanUpRegistrationsQuery := `DELETE FROM users WHERE email=$1 AND is_verified=false`
+	if _, rollBackErr = tx.Exec(cleanUpRegistrationsQuery, user.Email); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,164 @@
+package storage
+
+import "errors"
+
+type Follow struct {
+	FollowerId  int    `db:"follower_id" json:"follower_id"`
+	FollowingId int    `db:"following_id" json:"following_id"`
+	FollowedAt  string `db:"followed_at" json:"followed_at"`
+}
+
+type UserWithFollowCounts struct {
+	User
+	FollowersCount int `db:"followers_count" json:"followers_count"`
+	FollowingCount int `db:"following_count" json:"following_count"`
+}
+
+func (s *Storage) GetFollow(followerId int, followingId int) (*Follow, error) {
+
+	var follow Follow
+
+	query := `SELECT follower_id,following_id,followed_at
+	FROM follows WHERE follower_id=$1 AND following_id=$2`
+
+	if err := s.db.QueryRowx(query, followerId, followingId).StructScan(&follow); err != nil {
+		return nil, err
+	}
+
+	return &follow, nil
+}
+
+func (s *Storage) CreateFollow(followerId int, followingId int) (*Follow, error) {
+
+	var follow Follow
+
+	query := `INSERT INTO follows(follower_id,following_id) VALUES($1,$2)
+	RETURNING follower_id,following_id,followed_at`
+
+	if err := s.db.QueryRowx(query, followerId, followingId).StructScan(&follow); err != nil {
+		return nil, err
+	}
+
+	return &follow, nil
+}
+
+func (s *Storage) RemoveFollow(followerId int, followingId int) error {
+
+	query := `DELETE FROM follows WHERE follower_id=$1 AND following_id=$2`
+
+	result, err := s.db.Exec(query, followerId, followingId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return errors.New("failed to remove follow")
+	}
+
+	return nil
+}
+
+// GetUserFollowers public profiles of the users that follow userId (most recent followers first)
+func (s *Storage) GetUserFollowers(userId int, skip int, limit int) ([]UserProfile, error) {
+
+	var users []UserProfile
+
+	query := userProfileSelect + ` INNER JOIN follows AS f ON f.follower_id = users.id
+	WHERE f.following_id=$1
+	ORDER BY f.followed_at DESC
+	LIMIT $2 OFFSET $3`
+
+	rows, err := s.db.Queryx(query, userId, limit, skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user UserProfile
+
+		if err := rows.StructScan(&user); err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+func (s *Storage) GetUserFollowersCount(userId int) (int, error) {
+
+	var totalCount int
+
+	query := `SELECT COUNT(follower_id) FROM follows WHERE following_id=$1`
+
+	if err := s.db.QueryRowx(query, userId).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
+
+// GetUserFollowing public profiles of the users that userId follows (most recently followed first)
+func (s *Storage) GetUserFollowing(userId int, skip int, limit int) ([]UserProfile, error) {
+
+	var users []UserProfile
+
+	query := userProfileSelect + ` INNER JOIN follows AS f ON f.following_id = users.id
+	WHERE f.follower_id=$1
+	ORDER BY f.followed_at DESC
+	LIMIT $2 OFFSET $3`
+
+	rows, err := s.db.Queryx(query, userId, limit, skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user UserProfile
+
+		if err := rows.StructScan(&user); err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+func (s *Storage) GetUserFollowingCount(userId int) (int, error) {
+
+	var totalCount int
+
+	query := `SELECT COUNT(following_id) FROM follows WHERE follower_id=$1`
+
+	if err := s.db.QueryRowx(query, userId).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
+
+func (s *Storage) GetUserWithFollowCounts(userId int) (*UserWithFollowCounts, error) {
+
+	var user UserWithFollowCounts
+
+	query := `SELECT id, email, username, password, name, profile_img, is_verified, role, created_at, updated_at,
+	(SELECT COUNT(follower_id) FROM follows WHERE following_id=users.id) AS followers_count,
+	(SELECT COUNT(following_id) FROM follows WHERE follower_id=users.id) AS following_count
+	FROM users WHERE id=$1`
+
+	if err := s.db.QueryRowx(query, userId).StructScan(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
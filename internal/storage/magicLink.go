@@ -0,0 +1,78 @@
+package storage
+
+import "time"
+
+type MagicLink struct {
+	Token        string `db:"token" json:"token"`
+	UserId       int    `db:"user_id" json:"user_id"`
+	ExpirationAt string `db:"expiration_at" json:"expiration_at"`
+	CreatedAt    string `db:"created_at" json:"created_at"`
+}
+
+// CreateMagicLink removes any pending sign-in links for the user so that only the latest link works
+func (s *Storage) CreateMagicLink(userId int, token string, expirationAt time.Time) (*MagicLink, error) {
+
+	var magicLink MagicLink
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	cleanUpQuery := `DELETE FROM magic_links WHERE user_id=$1`
+	if _, rollBackErr = tx.Exec(cleanUpQuery, userId); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	query := `INSERT INTO magic_links(token,user_id,expiration_at) VALUES($1,$2,$3)
+	RETURNING token,user_id,expiration_at,created_at`
+
+	if rollBackErr = tx.QueryRowx(query, token, userId, expirationAt).StructScan(&magicLink); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	return &magicLink, nil
+}
+
+// ConsumeMagicLink deletes the link (single use) and returns its verified user,
+// sql.ErrNoRows if the token is invalid, already used or expired
+func (s *Storage) ConsumeMagicLink(token string) (*User, error) {
+
+	var user User
+
+	query := `WITH consumed_link AS (
+		DELETE FROM magic_links WHERE token=$1 AND expiration_at > $2 RETURNING user_id
+	)
+	SELECT users.id,users.email,users.username,users.password,users.name,users.profile_img,users.is_verified,users.role,users.created_at,users.updated_at 
+	FROM users INNER JOIN consumed_link ON users.id=consumed_link.user_id 
+	WHERE users.is_verified=true`
+
+	if err := s.db.QueryRowx(query, token, time.Now()).StructScan(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// DeleteExpiredMagicLinks sign-in links that expired before expiredBefore, returns the number deleted
+func (s *Storage) DeleteExpiredMagicLinks(expiredBefore time.Time) (int64, error) {
+
+	query := `DELETE FROM magic_links WHERE expiration_at < $1`
+
+	result, err := s.db.Exec(query, expiredBefore)
+	if err != nil {
+		return -1, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,93 @@
+package storage
+
+import "time"
+
+type PasswordReset struct {
+	Token        string `db:"token" json:"token"`
+	UserId       int    `db:"user_id" json:"user_id"`
+	ExpirationAt string `db:"expiration_at" json:"expiration_at"`
+}
+
+// CreatePasswordReset removes any pending reset tokens for the user so that only the latest reset link works
+func (s *Storage) CreatePasswordReset(userId int, token string, expirationAt time.Time) (*PasswordReset, error) {
+
+	var passwordReset PasswordReset
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	cleanUpQuery := `DELETE FROM password_resets WHERE user_id=$1`
+	if _, rollBackErr = tx.Exec(cleanUpQuery, userId); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	query := `INSERT INTO password_resets(token,user_id,expiration_at) VALUES($1,$2,$3)
+	RETURNING token,user_id,expiration_at`
+
+	if rollBackErr = tx.QueryRowx(query, token, userId, expirationAt).StructScan(&passwordReset); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	return &passwordReset, nil
+}
+
+// ResetUserPassword password passed in is already hashed, returns sql.ErrNoRows if the token is invalid or expired
+// all of the user's sessions are revoked along with the password change
+func (s *Storage) ResetUserPassword(token string, password string) (*User, error) {
+
+	var passwordReset PasswordReset
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `SELECT token,user_id,expiration_at FROM password_resets WHERE token=$1 AND expiration_at > $2`
+
+	if rollBackErr = tx.QueryRowx(query, token, time.Now()).StructScan(&passwordReset); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	var user User
+	updatePasswordQuery := `UPDATE users SET password=$1,updated_at=$2 WHERE id=$3 RETURNING
+	id,email,username,password,name,profile_img,is_verified,role,created_at,updated_at`
+
+	if rollBackErr = tx.QueryRowx(updatePasswordQuery, password, time.Now(), passwordReset.UserId).StructScan(&user); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	//	log the user out of every device that logged in with the old password
+	if rollBackErr = revokeUserSessionsTx(tx, user.Id); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	// reset tokens are single use, remove every pending token for this user
+	cleanUpQuery := `DELETE FROM password_resets WHERE user_id=$1`
+	if _, rollBackErr = tx.Exec(cleanUpQuery, user.Id); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	return &user, nil
+}
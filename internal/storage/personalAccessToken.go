@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// PersonalAccessToken named, scoped and expiring api token of a user, only its sha256 hash is stored
+type PersonalAccessToken struct {
+	Id         int            `db:"id" json:"id"`
+	UserId     int            `db:"user_id" json:"user_id"`
+	TokenName  string         `db:"token_name" json:"token_name"`
+	TokenHash  string         `db:"token_hash" json:"-"`
+	Scopes     pq.StringArray `db:"scopes" json:"scopes"`
+	CreatedAt  string         `db:"created_at" json:"created_at"`
+	ExpiresAt  string         `db:"expires_at" json:"expires_at"`
+	LastUsedAt *string        `db:"last_used_at" json:"last_used_at"`
+	RevokedAt  *string        `db:"revoked_at" json:"revoked_at"`
+}
+
+func (s *Storage) CreatePersonalAccessToken(userId int, tokenName string, tokenHash string, scopes []string, expiresAt time.Time) (*PersonalAccessToken, error) {
+
+	var personalAccessToken PersonalAccessToken
+
+	query := `INSERT INTO personal_access_tokens(user_id,token_name,token_hash,scopes,expires_at) VALUES($1,$2,$3,$4,$5) 
+	RETURNING id,user_id,token_name,token_hash,scopes,created_at,expires_at,last_used_at,revoked_at`
+
+	if err := s.db.QueryRowx(query, userId, tokenName, tokenHash, pq.StringArray(scopes), expiresAt).StructScan(&personalAccessToken); err != nil {
+		return nil, err
+	}
+
+	return &personalAccessToken, nil
+}
+
+// GetActivePersonalAccessTokenByHash returns sql.ErrNoRows if the token does not exist, is revoked or has expired
+func (s *Storage) GetActivePersonalAccessTokenByHash(tokenHash string) (*PersonalAccessToken, error) {
+
+	var personalAccessToken PersonalAccessToken
+
+	query := `SELECT id,user_id,token_name,token_hash,scopes,created_at,expires_at,last_used_at,revoked_at 
+	FROM personal_access_tokens WHERE token_hash=$1 AND revoked_at IS NULL AND expires_at > $2`
+
+	if err := s.db.QueryRowx(query, tokenHash, time.Now()).StructScan(&personalAccessToken); err != nil {
+		return nil, err
+	}
+
+	return &personalAccessToken, nil
+}
+
+func (s *Storage) GetPersonalAccessTokenById(personalAccessTokenId int) (*PersonalAccessToken, error) {
+
+	var personalAccessToken PersonalAccessToken
+
+	query := `SELECT id,user_id,token_name,token_hash,scopes,created_at,expires_at,last_used_at,revoked_at 
+	FROM personal_access_tokens WHERE id=$1`
+
+	if err := s.db.QueryRowx(query, personalAccessTokenId).StructScan(&personalAccessToken); err != nil {
+		return nil, err
+	}
+
+	return &personalAccessToken, nil
+}
+
+// GetUserPersonalAccessTokens tokens that are not revoked (expired ones included), newest first
+func (s *Storage) GetUserPersonalAccessTokens(userId int) ([]PersonalAccessToken, error) {
+
+	var personalAccessTokens []PersonalAccessToken
+
+	query := `SELECT id,user_id,token_name,token_hash,scopes,created_at,expires_at,last_used_at,revoked_at 
+	FROM personal_access_tokens WHERE user_id=$1 AND revoked_at IS NULL 
+	ORDER BY created_at DESC`
+
+	rows, err := s.db.Queryx(query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var personalAccessToken PersonalAccessToken
+
+		if err := rows.StructScan(&personalAccessToken); err != nil {
+			return nil, err
+		}
+
+		personalAccessTokens = append(personalAccessTokens, personalAccessToken)
+	}
+
+	return personalAccessTokens, nil
+}
+
+func (s *Storage) GetUserActivePersonalAccessTokensCount(userId int) (int, error) {
+
+	var totalCount int
+
+	query := `SELECT COUNT(id) FROM personal_access_tokens WHERE user_id=$1 AND revoked_at IS NULL AND expires_at > $2`
+
+	if err := s.db.QueryRowx(query, userId, time.Now()).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
+
+func (s *Storage) RevokePersonalAccessToken(personalAccessTokenId int) error {
+
+	query := `UPDATE personal_access_tokens SET revoked_at=$1 WHERE id=$2 AND revoked_at IS NULL`
+
+	_, err := s.db.Exec(query, time.Now(), personalAccessTokenId)
+	return err
+}
+
+// TouchPersonalAccessToken bumps last_used_at, at most once per minute
+func (s *Storage) TouchPersonalAccessToken(personalAccessTokenId int) error {
+
+	query := `UPDATE personal_access_tokens SET last_used_at=$1 WHERE id=$2 AND (last_used_at IS NULL OR last_used_at < $3)`
+
+	_, err := s.db.Exec(query, time.Now(), personalAccessTokenId, time.Now().Add(-time.Minute))
+	return err
+}
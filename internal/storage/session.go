@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// UserSession a login of a user, access tokens carry the session id and refresh tokens rotate within it
+type UserSession struct {
+	Id         int     `db:"id" json:"id"`
+	UserId     int     `db:"user_id" json:"user_id"`
+	CreatedAt  string  `db:"created_at" json:"created_at"`
+	LastSeenAt string  `db:"last_seen_at" json:"last_seen_at"`
+	ExpiresAt  string  `db:"expires_at" json:"expires_at"`
+	RevokedAt  *string `db:"revoked_at" json:"revoked_at"`
+	IpAddress  *string `db:"ip_address" json:"ip_address"` // ip address and user agent of the login
+	UserAgent  *string `db:"user_agent" json:"user_agent"`
+}
+
+type RefreshToken struct {
+	TokenHash string  `db:"token_hash" json:"-"`
+	SessionId int     `db:"session_id" json:"session_id"`
+	CreatedAt string  `db:"created_at" json:"created_at"`
+	ExpiresAt string  `db:"expires_at" json:"expires_at"`
+	UsedAt    *string `db:"used_at" json:"used_at"`
+}
+
+var (
+	// ErrRefreshTokenReused an already rotated refresh token was presented again, its session has been revoked
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// CreateUserSession starts a session for the user with its first refresh token
+func (s *Storage) CreateUserSession(userId int, refreshTokenHash string, expiresAt time.Time, ipAddress string, userAgent string) (*UserSession, error) {
+
+	var userSession UserSession
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `INSERT INTO user_sessions(user_id,expires_at,ip_address,user_agent) VALUES($1,$2,$3,$4) 
+	RETURNING id,user_id,created_at,last_seen_at,expires_at,revoked_at,ip_address,user_agent`
+
+	if rollBackErr = tx.QueryRowx(query, userId, expiresAt, ipAddress, userAgent).StructScan(&userSession); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	refreshTokenQuery := `INSERT INTO refresh_tokens(token_hash,session_id,expires_at) VALUES($1,$2,$3)`
+
+	if _, rollBackErr = tx.Exec(refreshTokenQuery, refreshTokenHash, userSession.Id, expiresAt); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	return &userSession, nil
+}
+
+// GetActiveUserSession returns sql.ErrNoRows if the session does not exist, is revoked or has expired
+func (s *Storage) GetActiveUserSession(sessionId int) (*UserSession, error) {
+
+	var userSession UserSession
+
+	query := `SELECT id,user_id,created_at,last_seen_at,expires_at,revoked_at,ip_address,user_agent 
+	FROM user_sessions WHERE id=$1 AND revoked_at IS NULL AND expires_at > $2`
+
+	if err := s.db.QueryRowx(query, sessionId, time.Now()).StructScan(&userSession); err != nil {
+		return nil, err
+	}
+
+	return &userSession, nil
+}
+
+// RotateRefreshToken exchanges refreshTokenHash for newRefreshTokenHash and extends the session until expiresAt.
+// returns sql.ErrNoRows for an unknown or expired token or an inactive session, and ErrRefreshTokenReused (after revoking
+// the session, so every token of the family stops working) when the token was already rotated
+func (s *Storage) RotateRefreshToken(refreshTokenHash string, newRefreshTokenHash string, expiresAt time.Time) (*UserSession, error) {
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	//	row locks serialize concurrent refreshes of the same token
+	var refreshToken RefreshToken
+	refreshTokenQuery := `SELECT token_hash,session_id,created_at,expires_at,used_at FROM refresh_tokens 
+	WHERE token_hash=$1 AND expires_at > $2 FOR UPDATE`
+
+	if rollBackErr = tx.QueryRowx(refreshTokenQuery, refreshTokenHash, time.Now()).StructScan(&refreshToken); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if refreshToken.UsedAt != nil {
+
+		revokeQuery := `UPDATE user_sessions SET revoked_at=$1 WHERE id=$2 AND revoked_at IS NULL`
+
+		if _, rollBackErr = tx.Exec(revokeQuery, time.Now(), refreshToken.SessionId); rollBackErr != nil {
+			return nil, rollBackErr
+		}
+
+		if rollBackErr = tx.Commit(); rollBackErr != nil {
+			return nil, rollBackErr
+		}
+
+		return nil, ErrRefreshTokenReused
+	}
+
+	var userSession UserSession
+	extendSessionQuery := `UPDATE user_sessions SET last_seen_at=$1,expires_at=$2 
+	WHERE id=$3 AND revoked_at IS NULL AND expires_at > $1
+	RETURNING id,user_id,created_at,last_seen_at,expires_at,revoked_at,ip_address,user_agent`
+
+	if rollBackErr = tx.QueryRowx(extendSessionQuery, time.Now(), expiresAt, refreshToken.SessionId).StructScan(&userSession); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	useTokenQuery := `UPDATE refresh_tokens SET used_at=$1 WHERE token_hash=$2`
+
+	if _, rollBackErr = tx.Exec(useTokenQuery, time.Now(), refreshToken.TokenHash); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	newTokenQuery := `INSERT INTO refresh_tokens(token_hash,session_id,expires_at) VALUES($1,$2,$3)`
+
+	if _, rollBackErr = tx.Exec(newTokenQuery, newRefreshTokenHash, userSession.Id, expiresAt); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	return &userSession, nil
+}
+
+// TouchUserSession bumps last_seen_at, at most once per minute so that authenticated requests rarely write
+func (s *Storage) TouchUserSession(sessionId int) error {
+
+	query := `UPDATE user_sessions SET last_seen_at=$1 WHERE id=$2 AND last_seen_at < $3`
+
+	_, err := s.db.Exec(query, time.Now(), sessionId, time.Now().Add(-time.Minute))
+	return err
+}
+
+// GetUserActiveSessions most recently used session first
+func (s *Storage) GetUserActiveSessions(userId int) ([]UserSession, error) {
+
+	var userSessions []UserSession
+
+	query := `SELECT id,user_id,created_at,last_seen_at,expires_at,revoked_at,ip_address,user_agent 
+	FROM user_sessions WHERE user_id=$1 AND revoked_at IS NULL AND expires_at > $2 
+	ORDER BY last_seen_at DESC`
+
+	rows, err := s.db.Queryx(query, userId, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userSession UserSession
+
+		if err := rows.StructScan(&userSession); err != nil {
+			return nil, err
+		}
+
+		userSessions = append(userSessions, userSession)
+	}
+
+	return userSessions, nil
+}
+
+// GetUserSessionIdByRefreshToken session the refresh token belongs to (whether or not the token was rotated)
+func (s *Storage) GetUserSessionIdByRefreshToken(refreshTokenHash string) (int, error) {
+
+	var sessionId int
+
+	query := `SELECT session_id FROM refresh_tokens WHERE token_hash=$1`
+
+	if err := s.db.QueryRowx(query, refreshTokenHash).Scan(&sessionId); err != nil {
+		return -1, err
+	}
+
+	return sessionId, nil
+}
+
+func (s *Storage) RevokeUserSession(sessionId int) error {
+
+	query := `UPDATE user_sessions SET revoked_at=$1 WHERE id=$2 AND revoked_at IS NULL`
+
+	_, err := s.db.Exec(query, time.Now(), sessionId)
+	return err
+}
+
+// RevokeUserSessions revokes every active session of the user ("log out everywhere")
+func (s *Storage) RevokeUserSessions(userId int) error {
+
+	query := `UPDATE user_sessions SET revoked_at=$1 WHERE user_id=$2 AND revoked_at IS NULL`
+
+	_, err := s.db.Exec(query, time.Now(), userId)
+	return err
+}
+
+// revokeUserSessionsTx same as RevokeUserSessions inside the caller's transaction
+func revokeUserSessionsTx(tx *sqlx.Tx, userId int) error {
+
+	query := `UPDATE user_sessions SET revoked_at=$1 WHERE user_id=$2 AND revoked_at IS NULL`
+
+	_, err := tx.Exec(query, time.Now(), userId)
+	return err
+}
+
+// DeleteEndedUserSessions sessions revoked or expired before endedBefore (their refresh tokens cascade),
+// returns the number deleted
+func (s *Storage) DeleteEndedUserSessions(endedBefore time.Time) (int64, error) {
+
+	query := `DELETE FROM user_sessions WHERE revoked_at < $1 OR expires_at < $1`
+
+	result, err := s.db.Exec(query, endedBefore)
+	if err != nil {
+		return -1, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteExpiredRefreshTokens refresh tokens (rotated or not) that expired before expiredBefore, an expired token
+// is rejected before it is checked for reuse so deleting it changes nothing, returns the number deleted
+func (s *Storage) DeleteExpiredRefreshTokens(expiredBefore time.Time) (int64, error) {
+
+	query := `DELETE FROM refresh_tokens WHERE expires_at < $1`
+
+	result, err := s.db.Exec(query, expiredBefore)
+	if err != nil {
+		return -1, err
+	}
+
+	return result.RowsAffected()
+}
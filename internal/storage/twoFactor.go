@@ -0,0 +1,266 @@
+package storage
+
+import (
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// UserTwoFactor totp settings of a user, enabled_at is null while the enrolment is not confirmed yet
+type UserTwoFactor struct {
+	UserId       int     `db:"user_id" json:"user_id"`
+	TotpSecret   string  `db:"totp_secret" json:"-"`
+	EnabledAt    *string `db:"enabled_at" json:"enabled_at"`
+	LastUsedStep *int64  `db:"last_used_step" json:"-"`
+	CreatedAt    string  `db:"created_at" json:"created_at"`
+}
+
+func (s *Storage) GetUserTwoFactor(userId int) (*UserTwoFactor, error) {
+
+	var userTwoFactor UserTwoFactor
+
+	query := `SELECT user_id,totp_secret,enabled_at,last_used_step,created_at FROM user_two_factor WHERE user_id=$1`
+
+	if err := s.db.QueryRowx(query, userId).StructScan(&userTwoFactor); err != nil {
+		return nil, err
+	}
+
+	return &userTwoFactor, nil
+}
+
+// IsUserTwoFactorEnabled false when the user never enrolled or has not confirmed the enrolment
+func (s *Storage) IsUserTwoFactorEnabled(userId int) (bool, error) {
+
+	var isEnabled bool
+
+	query := `SELECT EXISTS(SELECT 1 FROM user_two_factor WHERE user_id=$1 AND enabled_at IS NOT NULL)`
+
+	if err := s.db.QueryRowx(query, userId).Scan(&isEnabled); err != nil {
+		return false, err
+	}
+
+	return isEnabled, nil
+}
+
+// StartUserTwoFactorEnrolment stores a new unconfirmed secret, replacing a previous unconfirmed one
+func (s *Storage) StartUserTwoFactorEnrolment(userId int, totpSecret string) (*UserTwoFactor, error) {
+
+	var userTwoFactor UserTwoFactor
+
+	query := `INSERT INTO user_two_factor(user_id,totp_secret) VALUES($1,$2)
+	ON CONFLICT(user_id) DO UPDATE SET totp_secret=EXCLUDED.totp_secret,last_used_step=NULL,created_at=NOW()
+	WHERE user_two_factor.enabled_at IS NULL
+	RETURNING user_id,totp_secret,enabled_at,last_used_step,created_at`
+
+	if err := s.db.QueryRowx(query, userId, totpSecret).StructScan(&userTwoFactor); err != nil {
+		return nil, err
+	}
+
+	return &userTwoFactor, nil
+}
+
+// EnableUserTwoFactor confirms the enrolment with the time step of the confirming code and stores the recovery codes
+func (s *Storage) EnableUserTwoFactor(userId int, usedStep int64, recoveryCodeHashes []string) error {
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `UPDATE user_two_factor SET enabled_at=$1,last_used_step=$2 WHERE user_id=$3`
+
+	if _, rollBackErr = tx.Exec(query, time.Now(), usedStep, userId); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	if rollBackErr = replaceRecoveryCodesTx(tx, userId, recoveryCodeHashes); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	return nil
+}
+
+func (s *Storage) DisableUserTwoFactor(userId int) error {
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, rollBackErr = tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id=$1`, userId); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	if _, rollBackErr = tx.Exec(`DELETE FROM two_factor_challenges WHERE user_id=$1`, userId); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	if _, rollBackErr = tx.Exec(`DELETE FROM user_two_factor WHERE user_id=$1`, userId); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	return nil
+}
+
+// UseTotpStep marks the time step of a valid code as used, returns false if that step (or a later one)
+// was already used so the same code cannot be replayed
+func (s *Storage) UseTotpStep(userId int, step int64) (bool, error) {
+
+	query := `UPDATE user_two_factor SET last_used_step=$1 WHERE user_id=$2 AND (last_used_step IS NULL OR last_used_step < $1)`
+
+	result, err := s.db.Exec(query, step, userId)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (s *Storage) ReplaceRecoveryCodes(userId int, recoveryCodeHashes []string) error {
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if rollBackErr = replaceRecoveryCodesTx(tx, userId, recoveryCodeHashes); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	return nil
+}
+
+func replaceRecoveryCodesTx(tx *sqlx.Tx, userId int, recoveryCodeHashes []string) error {
+
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id=$1`, userId); err != nil {
+		return err
+	}
+
+	for _, recoveryCodeHash := range recoveryCodeHashes {
+		if _, err := tx.Exec(`INSERT INTO user_recovery_codes(user_id,code_hash) VALUES($1,$2)`, userId, recoveryCodeHash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCode recovery codes are single use, returns false if the code does not exist or was already used
+func (s *Storage) UseRecoveryCode(userId int, recoveryCodeHash string) (bool, error) {
+
+	query := `UPDATE user_recovery_codes SET used_at=$1 WHERE user_id=$2 AND code_hash=$3 AND used_at IS NULL`
+
+	result, err := s.db.Exec(query, time.Now(), userId, recoveryCodeHash)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (s *Storage) GetUnusedRecoveryCodesCount(userId int) (int, error) {
+
+	var totalCount int
+
+	query := `SELECT COUNT(id) FROM user_recovery_codes WHERE user_id=$1 AND used_at IS NULL`
+
+	if err := s.db.QueryRowx(query, userId).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
+
+func (s *Storage) CreateTwoFactorChallenge(userId int, tokenHash string, expiresAt time.Time) error {
+
+	query := `INSERT INTO two_factor_challenges(token_hash,user_id,expires_at) VALUES($1,$2,$3)`
+
+	_, err := s.db.Exec(query, tokenHash, userId, expiresAt)
+	return err
+}
+
+// AttemptTwoFactorChallenge counts an attempt against the challenge and returns its user,
+// sql.ErrNoRows if the challenge does not exist, has expired or has no attempts left
+func (s *Storage) AttemptTwoFactorChallenge(tokenHash string, maxAttempts int) (int, error) {
+
+	var userId int
+
+	query := `UPDATE two_factor_challenges SET attempts=attempts+1
+	WHERE token_hash=$1 AND expires_at > $2 AND attempts < $3
+	RETURNING user_id`
+
+	if err := s.db.QueryRowx(query, tokenHash, time.Now(), maxAttempts).Scan(&userId); err != nil {
+		return -1, err
+	}
+
+	return userId, nil
+}
+
+func (s *Storage) DeleteTwoFactorChallenge(tokenHash string) error {
+
+	query := `DELETE FROM two_factor_challenges WHERE token_hash=$1`
+
+	_, err := s.db.Exec(query, tokenHash)
+	return err
+}
+
+// DeleteUserTwoFactorChallenges invalidates every pending login challenge of the user
+func (s *Storage) DeleteUserTwoFactorChallenges(userId int) error {
+
+	query := `DELETE FROM two_factor_challenges WHERE user_id=$1`
+
+	_, err := s.db.Exec(query, userId)
+	return err
+}
+
+// DeleteExpiredTwoFactorChallenges challenges that expired before expiredBefore, returns the number deleted
+func (s *Storage) DeleteExpiredTwoFactorChallenges(expiredBefore time.Time) (int64, error) {
+
+	query := `DELETE FROM two_factor_challenges WHERE expires_at < $1`
+
+	result, err := s.db.Exec(query, expiredBefore)
+	if err != nil {
+		return -1, err
+	}
+
+	return result.RowsAffected()
+}
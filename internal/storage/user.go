@@ -1,12 +1,19 @@
 package storage
 
-import "time"
+import (
+	"errors"
+	"github.com/lib/pq"
+	"time"
+)
 
 type UserRole string
 
+// 'user','admin','moderator','editor', what each role may do is decided by the handlers' permission matrix
 const (
-	RoleUser  UserRole = "user"
-	RoleAdmin UserRole = "admin"
+	RoleUser      UserRole = "user"
+	RoleAdmin     UserRole = "admin"
+	RoleModerator UserRole = "moderator"
+	RoleEditor    UserRole = "editor"
 )
 
 type User struct {
@@ -22,6 +29,9 @@ type User struct {
 	UpdatedAt  *string  `db:"updated_at" json:"updated_at"`
 }
 
+// ErrUsernameTaken another user already has the username
+var ErrUsernameTaken = errors.New("username taken")
+
 type UserInvitation struct {
 	Token      string `db:"token" json:"token"`
 	UserId     int    `db:"user_id" json:"user_id"`
@@ -44,11 +54,12 @@ func (s *Storage) GetUserByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
+// GetVerifiedUserByEmail never returns the deleted user placeholder, it is verified but nobody can sign in as it
 func (s *Storage) GetVerifiedUserByEmail(email string) (*User, error) {
 	var user User
 
 	query := `SELECT id,id, email, username, password, name, profile_img, is_verified, role, created_at, updated_at 
-FROM users WHERE email=$1 AND is_verified=true`
+FROM users WHERE email=$1 AND is_verified=true AND is_placeholder=false`
 
 	row := s.db.QueryRowx(query, email)
 	if err := row.StructScan(&user); err != nil {
@@ -135,6 +146,12 @@ id,email,username,password,name,profile_img,is_verified,role,created_at,updated_
 		return nil, rollBackErr
 	}
 
+	// other pending registrations with the same email are abandoned duplicates
+	cleanUpDuplicatesQuery := `DELETE FROM users WHERE email=$1 AND is_verified=false AND id != $2`
+	if _, rollBackErr = tx.Exec(cleanUpDuplicatesQuery, activeUser.Email, activeUser.Id); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
 	if rollBackErr = tx.Commit(); rollBackErr != nil {
 		return nil, rollBackErr
 	}
@@ -142,6 +159,80 @@ id,email,username,password,name,profile_img,is_verified,role,created_at,updated_
 	return &activeUser, nil
 }
 
+// GetLatestUnverifiedUserByEmail the most recent pending registration for the email
+func (s *Storage) GetLatestUnverifiedUserByEmail(email string) (*User, error) {
+
+	var user User
+
+	query := `SELECT id, email, username, password, name, profile_img, is_verified, role, created_at, updated_at 
+FROM users WHERE email=$1 AND is_verified=false ORDER BY created_at DESC LIMIT 1`
+
+	if err := s.db.QueryRowx(query, email).StructScan(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// RenewUserInvitation replaces the user's pending invitations so that only the latest activation link works
+func (s *Storage) RenewUserInvitation(userId int, token string, inviteExpiration time.Time) error {
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	cleanUpQuery := `DELETE FROM user_invitations WHERE user_id=$1`
+	if _, rollBackErr = tx.Exec(cleanUpQuery, userId); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	invitationQuery := `INSERT INTO user_invitations(token,user_id,expiration) VALUES($1,$2,$3)`
+	if _, rollBackErr = tx.Exec(invitationQuery, token, userId, inviteExpiration); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	return nil
+}
+
+// DeleteExpiredUserInvitations invitations that expired before expiredBefore, returns the number deleted
+func (s *Storage) DeleteExpiredUserInvitations(expiredBefore time.Time) (int64, error) {
+
+	query := `DELETE FROM user_invitations WHERE expiration < $1`
+
+	result, err := s.db.Exec(query, expiredBefore)
+	if err != nil {
+		return -1, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteAbandonedUnverifiedUsers unverified users created before createdBefore that have no
+// unexpired invitation left (a resent activation keeps the account), returns the number deleted
+func (s *Storage) DeleteAbandonedUnverifiedUsers(createdBefore time.Time) (int64, error) {
+
+	query := `DELETE FROM users WHERE is_verified=false AND created_at < $1 
+	AND NOT EXISTS (SELECT 1 FROM user_invitations WHERE user_invitations.user_id=users.id AND user_invitations.expiration > $2)`
+
+	result, err := s.db.Exec(query, createdBefore, time.Now())
+	if err != nil {
+		return -1, err
+	}
+
+	return result.RowsAffected()
+}
+
 func (s *Storage) GetUserById(userId int) (*User, error) {
 
 	var user User
@@ -156,6 +247,21 @@ FROM users WHERE id=$1`
 	return &user, nil
 }
 
+// GetVerifiedUserById like GetVerifiedUserByEmail, never returns unverified users or the deleted user placeholder
+func (s *Storage) GetVerifiedUserById(userId int) (*User, error) {
+
+	var user User
+
+	query := `SELECT id, email, username, password, name, profile_img, is_verified, role, created_at, updated_at 
+FROM users WHERE id=$1 AND is_verified=true AND is_placeholder=false`
+
+	if err := s.db.QueryRowx(query, userId).StructScan(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 func (s *Storage) CreateVerifiedUser(email string, password string) (*User, error) {
 
 	var user User
@@ -169,3 +275,37 @@ func (s *Storage) CreateVerifiedUser(email string, password string) (*User, erro
 
 	return &user, nil
 }
+
+func (s *Storage) GetUserByUsername(username string) (*User, error) {
+
+	var user User
+
+	query := `SELECT id, email, username, password, name, profile_img, is_verified, role, created_at, updated_at 
+FROM users WHERE username=$1`
+
+	if err := s.db.QueryRowx(query, username).StructScan(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// UpdateUserProfile sets the user's public profile fields (nil clears a field)
+func (s *Storage) UpdateUserProfile(userId int, username *string, name *string, profileImg *string) (*User, error) {
+
+	var user User
+
+	query := `UPDATE users SET username=$1,name=$2,profile_img=$3,updated_at=$4 WHERE id=$5 RETURNING 
+	id,email,username,password,name,profile_img,is_verified,role,created_at,updated_at`
+
+	if err := s.db.QueryRowx(query, username, name, profileImg, time.Now(), userId).StructScan(&user); err != nil {
+		//	username is UNIQUE, a concurrent update can still take it after the handler's check
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
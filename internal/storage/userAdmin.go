@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// UserSuspension a suspension in effect, SuspendedUntil is nil for a ban
+type UserSuspension struct {
+	SuspendedAt      string  `db:"suspended_at" json:"suspended_at"`
+	SuspendedUntil   *string `db:"suspended_until" json:"suspended_until"`
+	SuspensionReason *string `db:"suspension_reason" json:"suspension_reason"`
+	SuspendedById    *int    `db:"suspended_by_id" json:"suspended_by_id"`
+}
+
+// UserWithAccountState a user as admins see it, Suspension is nil unless a suspension is in effect
+type UserWithAccountState struct {
+	User
+	IsTwoFactorEnabled  bool            `db:"is_two_factor_enabled" json:"is_two_factor_enabled"`
+	DeletionScheduledAt *string         `db:"deletion_scheduled_at" json:"deletion_scheduled_at"`
+	Suspension          *UserSuspension `db:"-" json:"suspension"`
+}
+
+// userWithAccountStateRow the suspension columns are scanned flat, a LEFT JOIN on suspended_users
+// leaves them NULL for users that are not suspended
+type userWithAccountStateRow struct {
+	UserWithAccountState
+	SuspendedAt      *string `db:"suspended_at"`
+	SuspendedUntil   *string `db:"suspended_until"`
+	SuspensionReason *string `db:"suspension_reason"`
+	SuspendedById    *int    `db:"suspended_by_id"`
+}
+
+func (row userWithAccountStateRow) toUserWithAccountState() UserWithAccountState {
+
+	user := row.UserWithAccountState
+	if row.SuspendedAt != nil {
+		user.Suspension = &UserSuspension{
+			SuspendedAt:      *row.SuspendedAt,
+			SuspendedUntil:   row.SuspendedUntil,
+			SuspensionReason: row.SuspensionReason,
+			SuspendedById:    row.SuspendedById,
+		}
+	}
+
+	return user
+}
+
+const userWithAccountStateColumns = `u.id,u.email,u.username,u.password,u.name,u.profile_img,u.is_verified,u.role,u.created_at,u.updated_at,
+	EXISTS(SELECT 1 FROM user_two_factor WHERE user_id=u.id AND enabled_at IS NOT NULL) AS is_two_factor_enabled,
+	u.deletion_scheduled_at,su.suspended_at,su.suspended_until,su.suspension_reason,su.suspended_by_id`
+
+// GetActiveUserSuspension returns sql.ErrNoRows if the user is not suspended right now
+func (s *Storage) GetActiveUserSuspension(userId int) (*UserSuspension, error) {
+
+	var userSuspension UserSuspension
+
+	query := `SELECT suspended_at,suspended_until,suspension_reason,suspended_by_id FROM suspended_users WHERE id=$1`
+
+	if err := s.db.QueryRowx(query, userId).StructScan(&userSuspension); err != nil {
+		return nil, err
+	}
+
+	return &userSuspension, nil
+}
+
+func (s *Storage) GetUserWithAccountState(userId int) (*UserWithAccountState, error) {
+
+	var row userWithAccountStateRow
+
+	query := `SELECT ` + userWithAccountStateColumns + ` FROM users AS u 
+	LEFT JOIN suspended_users AS su ON su.id=u.id WHERE u.id=$1 AND u.is_placeholder=false`
+
+	if err := s.db.QueryRowx(query, userId).StructScan(&row); err != nil {
+		return nil, err
+	}
+
+	user := row.toUserWithAccountState()
+	return &user, nil
+}
+
+// GetUsers - users matching the filters, newest first (paginated). searchText matches part of the
+// email or username, nil filters match every user
+func (s *Storage) GetUsers(searchText string, role *UserRole, isVerified *bool, isSuspended *bool, skip int, limit int) ([]UserWithAccountState, error) {
+
+	var users []UserWithAccountState
+
+	query := `SELECT ` + userWithAccountStateColumns + ` FROM users AS u 
+	LEFT JOIN suspended_users AS su ON su.id=u.id 
+	WHERE u.is_placeholder=false
+	AND ($1 = '' OR u.email ILIKE '%' || $1 || '%' ESCAPE '\' OR u.username ILIKE '%' || $1 || '%' ESCAPE '\')
+	AND ($2::user_role IS NULL OR u.role = $2)
+	AND ($3::boolean IS NULL OR u.is_verified = $3)
+	AND ($4::boolean IS NULL OR (su.id IS NOT NULL) = $4)
+	ORDER BY u.created_at DESC, u.id DESC
+	LIMIT $5 OFFSET $6`
+
+	rows, err := s.db.Queryx(query, escapeLikePattern(searchText), role, isVerified, isSuspended, limit, skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row userWithAccountStateRow
+
+		if err := rows.StructScan(&row); err != nil {
+			return nil, err
+		}
+
+		users = append(users, row.toUserWithAccountState())
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (s *Storage) GetUsersCount(searchText string, role *UserRole, isVerified *bool, isSuspended *bool) (int, error) {
+
+	var totalCount int
+
+	query := `SELECT COUNT(u.id) FROM users AS u 
+	LEFT JOIN suspended_users AS su ON su.id=u.id 
+	WHERE u.is_placeholder=false
+	AND ($1 = '' OR u.email ILIKE '%' || $1 || '%' ESCAPE '\' OR u.username ILIKE '%' || $1 || '%' ESCAPE '\')
+	AND ($2::user_role IS NULL OR u.role = $2)
+	AND ($3::boolean IS NULL OR u.is_verified = $3)
+	AND ($4::boolean IS NULL OR (su.id IS NOT NULL) = $4)`
+
+	if err := s.db.QueryRowx(query, escapeLikePattern(searchText), role, isVerified, isSuspended).Scan(&totalCount); err != nil {
+		return -1, err
+	}
+
+	return totalCount, nil
+}
+
+// escapeLikePattern the search text is matched literally, not as a LIKE pattern
+func escapeLikePattern(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+}
+
+func (s *Storage) UpdateUserRole(userId int, role UserRole) (*User, error) {
+
+	var user User
+
+	query := `UPDATE users SET role=$1,updated_at=$2 WHERE id=$3 AND is_placeholder=false RETURNING 
+	id,email,username,password,name,profile_img,is_verified,role,created_at,updated_at`
+
+	if err := s.db.QueryRowx(query, role, time.Now(), userId).StructScan(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// SuspendUser suspends the user until suspendedUntil, a nil suspendedUntil bans the user.
+// suspending an already suspended user replaces the suspension, suspendedById is nil when
+// there is no acting user (the admin cli)
+func (s *Storage) SuspendUser(userId int, suspendedById *int, suspendedUntil *time.Time, suspensionReason string) error {
+
+	query := `UPDATE users SET suspended_at=$1,suspended_until=$2,suspension_reason=NULLIF($3,''),suspended_by_id=$4 
+	WHERE id=$5 AND is_placeholder=false`
+
+	result, err := s.db.Exec(query, time.Now(), suspendedUntil, suspensionReason, suspendedById, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s *Storage) UnsuspendUser(userId int) error {
+
+	query := `UPDATE users SET suspended_at=NULL,suspended_until=NULL,suspension_reason=NULL,suspended_by_id=NULL WHERE id=$1`
+
+	_, err := s.db.Exec(query, userId)
+	return err
+}
+
+// ForceVerifyUser verifies the user without the activation link, returns ErrEmailTaken if another
+// verified user owns the email. like ActivateUser, other pending registrations for the email are removed
+func (s *Storage) ForceVerifyUser(userId int) (*User, error) {
+
+	var user User
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	userQuery := `SELECT id,email,username,password,name,profile_img,is_verified,role,created_at,updated_at 
+	FROM users WHERE id=$1 AND is_placeholder=false FOR UPDATE`
+
+	if rollBackErr = tx.QueryRowx(userQuery, userId).StructScan(&user); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	var isEmailTaken bool
+
+	emailTakenQuery := `SELECT EXISTS(SELECT 1 FROM users WHERE email=$1 AND is_verified=true AND is_placeholder=false AND id != $2)`
+	if rollBackErr = tx.QueryRowx(emailTakenQuery, user.Email, user.Id).Scan(&isEmailTaken); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if isEmailTaken {
+		rollBackErr = ErrEmailTaken
+		return nil, rollBackErr
+	}
+
+	verifyUserQuery := `UPDATE users SET is_verified=true,updated_at=$1 WHERE id=$2 RETURNING 
+	id,email,username,password,name,profile_img,is_verified,role,created_at,updated_at`
+
+	if rollBackErr = tx.QueryRowx(verifyUserQuery, time.Now(), user.Id).StructScan(&user); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	cleanUpInvitationsQuery := `DELETE FROM user_invitations WHERE user_id=$1`
+	if _, rollBackErr = tx.Exec(cleanUpInvitationsQuery, user.Id); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	cleanUpDuplicatesQuery := `DELETE FROM users WHERE email=$1 AND is_verified=false AND id != $2`
+	if _, rollBackErr = tx.Exec(cleanUpDuplicatesQuery, user.Email, user.Id); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	return &user, nil
+}
+
+// SetUserPassword password passed in is already hashed, like ResetUserPassword all of the user's
+// sessions are revoked and pending password resets removed
+func (s *Storage) SetUserPassword(userId int, password string) (*User, error) {
+
+	var user User
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	updatePasswordQuery := `UPDATE users SET password=$1,updated_at=$2 WHERE id=$3 AND is_placeholder=false RETURNING
+	id,email,username,password,name,profile_img,is_verified,role,created_at,updated_at`
+
+	if rollBackErr = tx.QueryRowx(updatePasswordQuery, password, time.Now(), userId).StructScan(&user); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if rollBackErr = revokeUserSessionsTx(tx, user.Id); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	cleanUpQuery := `DELETE FROM password_resets WHERE user_id=$1`
+	if _, rollBackErr = tx.Exec(cleanUpQuery, user.Id); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ScheduleUserDeletion marks the user for deletion at scheduledAt and signs them out everywhere
+// (sessions and personal access tokens), keepContent decides whether the user's published blogs
+// and comments survive the purge attributed to the "deleted user" placeholder
+func (s *Storage) ScheduleUserDeletion(userId int, scheduledAt time.Time, keepContent bool) error {
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `UPDATE users SET deletion_scheduled_at=$1,deletion_keeps_content=$2,updated_at=$3 
+	WHERE id=$4 AND is_placeholder=false`
+
+	var result sql.Result
+	if result, rollBackErr = tx.Exec(query, scheduledAt, keepContent, time.Now(), userId); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	var rowsAffected int64
+	if rowsAffected, rollBackErr = result.RowsAffected(); rollBackErr != nil {
+		return rollBackErr
+	}
+	if rowsAffected == 0 {
+		rollBackErr = sql.ErrNoRows
+		return rollBackErr
+	}
+
+	if rollBackErr = revokeUserSessionsTx(tx, userId); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	revokeTokensQuery := `UPDATE personal_access_tokens SET revoked_at=$1 WHERE user_id=$2 AND revoked_at IS NULL`
+	if _, rollBackErr = tx.Exec(revokeTokensQuery, time.Now(), userId); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return rollBackErr
+	}
+
+	return nil
+}
+
+// CancelUserDeletion returns true if the user had a deletion scheduled
+func (s *Storage) CancelUserDeletion(userId int) (bool, error) {
+
+	query := `UPDATE users SET deletion_scheduled_at=NULL,updated_at=$1 WHERE id=$2 AND deletion_scheduled_at IS NOT NULL`
+
+	result, err := s.db.Exec(query, time.Now(), userId)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// GetUserIdsDueForDeletion users whose grace period ended before dueBefore, oldest first
+func (s *Storage) GetUserIdsDueForDeletion(dueBefore time.Time, limit int) ([]int, error) {
+
+	var userIds []int
+
+	query := `SELECT id FROM users WHERE deletion_scheduled_at <= $1 AND is_placeholder=false 
+	ORDER BY deletion_scheduled_at ASC LIMIT $2`
+
+	if err := s.db.Select(&userIds, query, dueBefore, limit); err != nil {
+		return nil, err
+	}
+
+	return userIds, nil
+}
+
+// PurgeUser deletes a user whose scheduled deletion is due, everything owned by the user cascades
+// except kept content, which is moved to the placeholder user first. returns false if the
+// deletion was cancelled (or the user removed) in the meantime
+func (s *Storage) PurgeUser(userId int) (bool, error) {
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var keepsContent bool
+
+	//	the row lock makes a concurrent login (which cancels the deletion) wait for the purge or win over it
+	query := `SELECT deletion_keeps_content FROM users WHERE id=$1 AND deletion_scheduled_at <= $2 AND is_placeholder=false FOR UPDATE`
+
+	if rollBackErr = tx.QueryRowx(query, userId, time.Now()).Scan(&keepsContent); rollBackErr != nil {
+		if errors.Is(rollBackErr, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, rollBackErr
+	}
+
+	if keepsContent {
+
+		var placeholderUserId int
+
+		placeholderQuery := `SELECT id FROM users WHERE is_placeholder=true`
+		if rollBackErr = tx.QueryRowx(placeholderQuery).Scan(&placeholderUserId); rollBackErr != nil {
+			return false, rollBackErr
+		}
+
+		//	drafts, scheduled and archived blogs are never public, they go with the user
+		keepBlogsQuery := `UPDATE blogs SET blog_author_id=$1 WHERE blog_author_id=$2 AND blog_status='published'`
+		if _, rollBackErr = tx.Exec(keepBlogsQuery, placeholderUserId, userId); rollBackErr != nil {
+			return false, rollBackErr
+		}
+
+		//	keeping every comment keeps the replies of other users under them
+		keepCommentsQuery := `UPDATE blog_comments SET comment_author_id=$1 WHERE comment_author_id=$2`
+		if _, rollBackErr = tx.Exec(keepCommentsQuery, placeholderUserId, userId); rollBackErr != nil {
+			return false, rollBackErr
+		}
+	}
+
+	deleteUserQuery := `DELETE FROM users WHERE id=$1`
+	if _, rollBackErr = tx.Exec(deleteUserQuery, userId); rollBackErr != nil {
+		return false, rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return false, rollBackErr
+	}
+
+	return true, nil
+}
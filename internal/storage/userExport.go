@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+)
+
+// ExportedBlog a blog of the user with the ids of its topics
+type ExportedBlog struct {
+	Blog
+	BlogTopicIds pq.Int64Array `db:"blog_topic_ids" json:"blog_topic_ids"`
+}
+
+// ExportedTopicFollow a followed topic with its name
+type ExportedTopicFollow struct {
+	TopicFollow
+	TopicName string `db:"topic_name" json:"topic_name"`
+}
+
+// UserDataExport everything GET /me/export hands back to the user
+type UserDataExport struct {
+	User             User
+	Identities       []UserIdentity
+	Blogs            []ExportedBlog
+	BlogComments     []BlogComment
+	BlogLikes        []BlogLike
+	BlogCommentLikes []BlogCommentLike
+	BookmarkFolders  []BookmarkFolder
+	BlogBookmarks    []BlogBookmark
+	TopicFollows     []ExportedTopicFollow
+}
+
+// GetUserDataExport reads all of the user's data in one read only repeatable read transaction,
+// so that the export is a consistent snapshot
+func (s *Storage) GetUserDataExport(userId int) (*UserDataExport, error) {
+
+	var userDataExport UserDataExport
+
+	tx, err := s.db.BeginTxx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	userQuery := `SELECT id,email,username,password,name,profile_img,is_verified,role,created_at,updated_at 
+	FROM users WHERE id=$1`
+
+	if err := tx.QueryRowx(userQuery, userId).StructScan(&userDataExport.User); err != nil {
+		return nil, err
+	}
+
+	identitiesQuery := `SELECT id,user_id,provider,subject,email,created_at,last_login_at FROM user_identities 
+	WHERE user_id=$1 ORDER BY created_at ASC`
+
+	if err := tx.Select(&userDataExport.Identities, identitiesQuery, userId); err != nil {
+		return nil, err
+	}
+
+	blogsQuery := `SELECT b.id,b.blog_title,b.blog_description,b.blog_content,b.blog_thumbnail,b.blog_status,
+	b.blog_author_id,b.published_at,b.blog_created_at,b.blog_updated_at,b.blog_version,b.publish_at,
+	ARRAY(SELECT topic_id FROM blog_topics WHERE blog_id=b.id ORDER BY topic_id) AS blog_topic_ids
+	FROM blogs AS b WHERE b.blog_author_id=$1 ORDER BY b.blog_created_at ASC`
+
+	if err := tx.Select(&userDataExport.Blogs, blogsQuery, userId); err != nil {
+		return nil, err
+	}
+
+	blogCommentsQuery := `SELECT id,blog_comment,comment_author_id,blog_id,parent_comment_id,comment_created_at,comment_updated_at 
+	FROM blog_comments WHERE comment_author_id=$1 ORDER BY comment_created_at ASC`
+
+	if err := tx.Select(&userDataExport.BlogComments, blogCommentsQuery, userId); err != nil {
+		return nil, err
+	}
+
+	blogLikesQuery := `SELECT liked_by_id,liked_blog_id,liked_at FROM blog_likes WHERE liked_by_id=$1 ORDER BY liked_at ASC`
+
+	if err := tx.Select(&userDataExport.BlogLikes, blogLikesQuery, userId); err != nil {
+		return nil, err
+	}
+
+	blogCommentLikesQuery := `SELECT liked_by_id,liked_blog_comment_id,liked_at FROM blog_comment_likes 
+	WHERE liked_by_id=$1 ORDER BY liked_at ASC`
+
+	if err := tx.Select(&userDataExport.BlogCommentLikes, blogCommentLikesQuery, userId); err != nil {
+		return nil, err
+	}
+
+	bookmarkFoldersQuery := `SELECT id,user_id,folder_name,created_at,updated_at,
+	(SELECT COUNT(bookmarked_blog_id) FROM blog_bookmarks WHERE bookmark_folder_id=bookmark_folders.id) AS bookmarks_count
+	FROM bookmark_folders WHERE user_id=$1 ORDER BY folder_name`
+
+	if err := tx.Select(&userDataExport.BookmarkFolders, bookmarkFoldersQuery, userId); err != nil {
+		return nil, err
+	}
+
+	blogBookmarksQuery := `SELECT bookmarked_by_id,bookmarked_blog_id,bookmarked_at,bookmark_folder_id FROM blog_bookmarks 
+	WHERE bookmarked_by_id=$1 ORDER BY bookmarked_at ASC`
+
+	if err := tx.Select(&userDataExport.BlogBookmarks, blogBookmarksQuery, userId); err != nil {
+		return nil, err
+	}
+
+	topicFollowsQuery := `SELECT tf.user_id,tf.topic_id,tf.followed_at,t.topic_name FROM topic_follows AS tf 
+	INNER JOIN topics AS t ON t.id=tf.topic_id WHERE tf.user_id=$1 ORDER BY tf.followed_at ASC`
+
+	if err := tx.Select(&userDataExport.TopicFollows, topicFollowsQuery, userId); err != nil {
+		return nil, err
+	}
+
+	return &userDataExport, nil
+}
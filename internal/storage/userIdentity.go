@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"time"
+)
+
+// UserIdentity an external login (oauth / oidc provider account) linked to a user, a user can link several
+type UserIdentity struct {
+	Id          int     `db:"id" json:"id"`
+	UserId      int     `db:"user_id" json:"user_id"`
+	Provider    string  `db:"provider" json:"provider"`
+	Subject     string  `db:"subject" json:"-"`
+	Email       *string `db:"email" json:"email"`
+	CreatedAt   string  `db:"created_at" json:"created_at"`
+	LastLoginAt *string `db:"last_login_at" json:"last_login_at"`
+}
+
+func (s *Storage) GetUserIdentity(provider string, subject string) (*UserIdentity, error) {
+
+	var userIdentity UserIdentity
+
+	query := `SELECT id,user_id,provider,subject,email,created_at,last_login_at FROM user_identities WHERE provider=$1 AND subject=$2`
+
+	if err := s.db.QueryRowx(query, provider, subject).StructScan(&userIdentity); err != nil {
+		return nil, err
+	}
+
+	return &userIdentity, nil
+}
+
+func (s *Storage) GetUserIdentityById(userIdentityId int) (*UserIdentity, error) {
+
+	var userIdentity UserIdentity
+
+	query := `SELECT id,user_id,provider,subject,email,created_at,last_login_at FROM user_identities WHERE id=$1`
+
+	if err := s.db.QueryRowx(query, userIdentityId).StructScan(&userIdentity); err != nil {
+		return nil, err
+	}
+
+	return &userIdentity, nil
+}
+
+func (s *Storage) GetUserIdentities(userId int) ([]UserIdentity, error) {
+
+	var userIdentities []UserIdentity
+
+	query := `SELECT id,user_id,provider,subject,email,created_at,last_login_at FROM user_identities
+	WHERE user_id=$1 ORDER BY created_at ASC`
+
+	rows, err := s.db.Queryx(query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userIdentity UserIdentity
+
+		if err := rows.StructScan(&userIdentity); err != nil {
+			return nil, err
+		}
+
+		userIdentities = append(userIdentities, userIdentity)
+	}
+
+	return userIdentities, nil
+}
+
+func (s *Storage) CreateUserIdentity(userId int, provider string, subject string, email string) (*UserIdentity, error) {
+
+	var userIdentity UserIdentity
+
+	query := `INSERT INTO user_identities(user_id,provider,subject,email,last_login_at) VALUES($1,$2,$3,NULLIF($4,''),$5)
+	RETURNING id,user_id,provider,subject,email,created_at,last_login_at`
+
+	if err := s.db.QueryRowx(query, userId, provider, subject, email, time.Now()).StructScan(&userIdentity); err != nil {
+		return nil, err
+	}
+
+	return &userIdentity, nil
+}
+
+// CreateUserWithIdentity signs up a verified user from a provider login, the password is an unusable
+// random hash (the user can set one with forgot password)
+func (s *Storage) CreateUserWithIdentity(email string, password string, name *string, profileImg *string, provider string, subject string) (*User, error) {
+
+	var user User
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	var rollBackErr error
+	defer func() {
+		if rollBackErr != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `INSERT INTO users(email,password,name,profile_img,is_verified) VALUES($1,$2,$3,$4,true) RETURNING
+id,email,username,password,name,profile_img,is_verified,role,created_at,updated_at`
+
+	if rollBackErr = tx.QueryRowx(query, email, password, name, profileImg).StructScan(&user); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	identityQuery := `INSERT INTO user_identities(user_id,provider,subject,email,last_login_at) VALUES($1,$2,$3,$4,$5)`
+
+	if _, rollBackErr = tx.Exec(identityQuery, user.Id, provider, subject, email, time.Now()); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	if rollBackErr = tx.Commit(); rollBackErr != nil {
+		return nil, rollBackErr
+	}
+
+	return &user, nil
+}
+
+func (s *Storage) TouchUserIdentity(userIdentityId int) error {
+
+	query := `UPDATE user_identities SET last_login_at=$1 WHERE id=$2`
+
+	_, err := s.db.Exec(query, time.Now(), userIdentityId)
+	return err
+}
+
+func (s *Storage) DeleteUserIdentity(userIdentityId int) error {
+
+	query := `DELETE FROM user_identities WHERE id=$1`
+
+	_, err := s.db.Exec(query, userIdentityId)
+	return err
+}
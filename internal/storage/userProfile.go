@@ -0,0 +1,49 @@
+package storage
+
+// UserProfile public view of a user, it never includes the user's email, password or role
+type UserProfile struct {
+	Id                  int     `db:"id" json:"id"`
+	Username            *string `db:"username" json:"username"`
+	Name                *string `db:"name" json:"name"`
+	ProfileImg          *string `db:"profile_img" json:"profile_img"`
+	CreatedAt           string  `db:"created_at" json:"created_at"`
+	PublishedBlogsCount int     `db:"published_blogs_count" json:"published_blogs_count"`
+	LikesReceivedCount  int     `db:"likes_received_count" json:"likes_received_count"`
+	FollowersCount      int     `db:"followers_count" json:"followers_count"`
+	FollowingCount      int     `db:"following_count" json:"following_count"`
+}
+
+const userProfileSelect = `SELECT id, username, name, profile_img, created_at,
+	(SELECT COUNT(id) FROM blogs WHERE blog_author_id=users.id AND blog_status='published') AS published_blogs_count,
+	(SELECT COUNT(bl.liked_by_id) FROM blog_likes AS bl INNER JOIN blogs AS b ON bl.liked_blog_id=b.id
+	 WHERE b.blog_author_id=users.id AND b.blog_status='published') AS likes_received_count,
+	(SELECT COUNT(follower_id) FROM follows WHERE following_id=users.id) AS followers_count,
+	(SELECT COUNT(following_id) FROM follows WHERE follower_id=users.id) AS following_count
+	FROM users`
+
+// GetUserProfileById only verified users have a public profile
+func (s *Storage) GetUserProfileById(userId int) (*UserProfile, error) {
+
+	var userProfile UserProfile
+
+	query := userProfileSelect + ` WHERE id=$1 AND is_verified=true`
+
+	if err := s.db.QueryRowx(query, userId).StructScan(&userProfile); err != nil {
+		return nil, err
+	}
+
+	return &userProfile, nil
+}
+
+func (s *Storage) GetUserProfileByUsername(username string) (*UserProfile, error) {
+
+	var userProfile UserProfile
+
+	query := userProfileSelect + ` WHERE username=$1 AND is_verified=true`
+
+	if err := s.db.QueryRowx(query, username).StructScan(&userProfile); err != nil {
+		return nil, err
+	}
+
+	return &userProfile, nil
+}
@@ -0,0 +1,15 @@
+
+
+CREATE TABLE IF NOT EXISTS blog_revisions(
+    id SERIAL PRIMARY KEY,
+    blog_id INTEGER NOT NULL,
+    revision_number INTEGER NOT NULL,
+    blog_title TEXT NOT NULL,
+    blog_description TEXT,
+    blog_content JSONB NOT NULL,
+    blog_thumbnail TEXT,
+    blog_topic_ids INTEGER[] NOT NULL DEFAULT '{}',
+    revision_created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY(blog_id) REFERENCES blogs(id) ON DELETE CASCADE,
+    UNIQUE(blog_id,revision_number)
+);
@@ -0,0 +1,4 @@
+
+
+ALTER TABLE blogs
+DROP COLUMN blog_version;
@@ -0,0 +1,4 @@
+
+
+ALTER TABLE blogs
+ADD COLUMN blog_version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,9 @@
+
+
+DROP INDEX IF EXISTS idx_blogs_scheduled_publish_at;
+
+-- postgres cannot drop an enum value, scheduled blogs go back to being drafts
+UPDATE blogs SET blog_status='draft' WHERE blog_status='scheduled';
+
+ALTER TABLE blogs
+DROP COLUMN publish_at;
@@ -0,0 +1,8 @@
+
+
+ALTER TYPE blog_status ADD VALUE IF NOT EXISTS 'scheduled';
+
+ALTER TABLE blogs
+ADD COLUMN publish_at TIMESTAMP;
+
+CREATE INDEX idx_blogs_scheduled_publish_at ON blogs(publish_at) WHERE publish_at IS NOT NULL;
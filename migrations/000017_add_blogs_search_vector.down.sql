@@ -0,0 +1,6 @@
+
+
+DROP INDEX IF EXISTS idx_blogs_search_vector;
+
+ALTER TABLE blogs
+DROP COLUMN blog_search_vector;
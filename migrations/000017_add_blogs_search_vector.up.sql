@@ -0,0 +1,11 @@
+
+
+-- weighted search document: title (A) > description (B) > text values of the blog_content JSONB (C)
+ALTER TABLE blogs
+ADD COLUMN blog_search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(blog_title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(blog_description, '')), 'B') ||
+    setweight(jsonb_to_tsvector('english', blog_content, '["string"]'), 'C')
+) STORED;
+
+CREATE INDEX idx_blogs_search_vector ON blogs USING GIN(blog_search_vector);
@@ -0,0 +1,9 @@
+
+
+DROP INDEX IF EXISTS idx_blog_likes_liked_by_id;
+DROP INDEX IF EXISTS idx_blog_bookmarks_bookmarked_by_id;
+
+ALTER TABLE blog_bookmarks
+DROP COLUMN bookmark_folder_id;
+
+DROP TABLE IF EXISTS bookmark_folders;
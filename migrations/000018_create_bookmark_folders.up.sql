@@ -0,0 +1,18 @@
+
+
+CREATE TABLE IF NOT EXISTS bookmark_folders(
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    folder_name TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(user_id,folder_name)
+);
+
+-- deleting a folder keeps its bookmarks, they become unfiled
+ALTER TABLE blog_bookmarks
+ADD COLUMN bookmark_folder_id INTEGER REFERENCES bookmark_folders(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_blog_bookmarks_bookmarked_by_id ON blog_bookmarks(bookmarked_by_id, bookmarked_at DESC);
+CREATE INDEX idx_blog_likes_liked_by_id ON blog_likes(liked_by_id, liked_at DESC);
@@ -0,0 +1,25 @@
+
+
+-- one row per login, refresh tokens rotate within a session (the session is the refresh token family)
+CREATE TABLE IF NOT EXISTS user_sessions(
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    last_seen_at TIMESTAMP DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_sessions_user_id ON user_sessions(user_id);
+
+-- rotated tokens are kept (used_at set) so that a replayed refresh token can be detected
+CREATE TABLE IF NOT EXISTS refresh_tokens(
+    token_hash TEXT PRIMARY KEY,
+    session_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    used_at TIMESTAMP,
+    FOREIGN KEY(session_id) REFERENCES user_sessions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_refresh_tokens_session_id ON refresh_tokens(session_id);
@@ -0,0 +1,5 @@
+
+
+ALTER TABLE user_sessions
+DROP COLUMN ip_address,
+DROP COLUMN user_agent;
@@ -0,0 +1,5 @@
+
+
+ALTER TABLE user_sessions
+ADD COLUMN ip_address TEXT,
+ADD COLUMN user_agent TEXT;
@@ -0,0 +1,3 @@
+
+
+DROP TABLE IF EXISTS personal_access_tokens;
@@ -0,0 +1,11 @@
+
+
+CREATE TABLE IF NOT EXISTS magic_links(
+    token TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    expiration_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_magic_links_user_id ON magic_links(user_id);
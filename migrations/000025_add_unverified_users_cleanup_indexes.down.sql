@@ -0,0 +1,6 @@
+
+
+DROP INDEX IF EXISTS idx_user_invitations_expiration;
+DROP INDEX IF EXISTS idx_user_invitations_user_id;
+DROP INDEX IF EXISTS idx_users_email;
+DROP INDEX IF EXISTS idx_users_unverified_created_at;
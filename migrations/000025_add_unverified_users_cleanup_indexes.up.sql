@@ -0,0 +1,6 @@
+
+
+CREATE INDEX IF NOT EXISTS idx_users_unverified_created_at ON users(created_at) WHERE is_verified=false;
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+CREATE INDEX IF NOT EXISTS idx_user_invitations_user_id ON user_invitations(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_invitations_expiration ON user_invitations(expiration);
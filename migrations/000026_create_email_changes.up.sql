@@ -0,0 +1,12 @@
+
+
+CREATE TABLE IF NOT EXISTS email_changes(
+    token TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    new_email TEXT NOT NULL,
+    expiration_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_email_changes_user_id ON email_changes(user_id);
@@ -0,0 +1,12 @@
+
+
+-- blogs and comments kept for deleted users are removed along with the placeholder
+DELETE FROM users WHERE is_placeholder=true;
+
+DROP INDEX IF EXISTS idx_users_placeholder;
+DROP INDEX IF EXISTS idx_users_deletion_scheduled_at;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS is_placeholder,
+DROP COLUMN IF EXISTS deletion_keeps_content,
+DROP COLUMN IF EXISTS deletion_scheduled_at;
@@ -0,0 +1,16 @@
+
+
+-- DELETE /me only schedules the deletion, the accountCleanup worker purges the user once deletion_scheduled_at passes
+ALTER TABLE users
+ADD COLUMN deletion_scheduled_at TIMESTAMP,
+ADD COLUMN deletion_keeps_content BOOLEAN NOT NULL DEFAULT TRUE,
+ADD COLUMN is_placeholder BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_at ON users(deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
+
+-- the "deleted user" that kept published blogs and comments are attributed to, '!' is not a bcrypt hash
+-- so nobody can log in as it and the .invalid address never receives mail
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_placeholder ON users(is_placeholder) WHERE is_placeholder=true;
+
+INSERT INTO users(email,password,name,is_verified,is_placeholder)
+VALUES('deleted-user@deleted.invalid','!','Deleted user',true,true);
@@ -0,0 +1,9 @@
+
+
+DROP INDEX IF EXISTS idx_blogs_featured_at;
+
+ALTER TABLE blogs
+DROP COLUMN featured_at;
+
+-- postgres cannot drop an enum value, moderators and editors go back to being users
+UPDATE users SET role='user' WHERE role IN ('moderator','editor');
@@ -0,0 +1,10 @@
+
+
+ALTER TYPE user_role ADD VALUE IF NOT EXISTS 'moderator';
+ALTER TYPE user_role ADD VALUE IF NOT EXISTS 'editor';
+
+-- set while the blog is featured (blog:feature permission)
+ALTER TABLE blogs
+ADD COLUMN featured_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_blogs_featured_at ON blogs(featured_at DESC) WHERE featured_at IS NOT NULL;
@@ -0,0 +1,11 @@
+
+
+DROP VIEW IF EXISTS suspended_users;
+
+DROP INDEX IF EXISTS idx_users_suspended_at;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS suspended_by_id,
+DROP COLUMN IF EXISTS suspension_reason,
+DROP COLUMN IF EXISTS suspended_until,
+DROP COLUMN IF EXISTS suspended_at;
@@ -0,0 +1,16 @@
+
+
+-- suspended_until NULL with suspended_at set is a ban (no end)
+ALTER TABLE users
+ADD COLUMN suspended_at TIMESTAMP,
+ADD COLUMN suspended_until TIMESTAMP,
+ADD COLUMN suspension_reason TEXT,
+ADD COLUMN suspended_by_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_suspended_at ON users(suspended_at) WHERE suspended_at IS NOT NULL;
+
+-- users whose suspension is in effect right now, feeds leave out their blogs
+CREATE OR REPLACE VIEW suspended_users AS
+SELECT id, suspended_at, suspended_until, suspension_reason, suspended_by_id
+FROM users
+WHERE suspended_at IS NOT NULL AND (suspended_until IS NULL OR suspended_until > NOW());
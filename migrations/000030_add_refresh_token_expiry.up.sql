@@ -0,0 +1,11 @@
+
+
+-- a refresh token expires REFRESH_TOKEN_EXPIRATION after it was issued, rotated tokens are kept until then so that
+-- a replay revokes the session for as long as the token could have been used
+ALTER TABLE refresh_tokens ADD COLUMN expires_at TIMESTAMP;
+
+UPDATE refresh_tokens SET expires_at = created_at + INTERVAL '30 days';
+
+ALTER TABLE refresh_tokens ALTER COLUMN expires_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
@@ -0,0 +1,51 @@
+package scripts
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+)
+
+// ArchiveBlog archives a published blog, like PATCH /api/blog/{blogId}/status does for its author
+func (s *Script) ArchiveBlog(blogId int) (*storage.BlogWithUserAndTopics, error) {
+
+	blog, err := s.getBlog(blogId)
+	if err != nil {
+		return nil, err
+	}
+
+	if blog.BlogStatus != storage.BlogStatusPublished {
+		return nil, fmt.Errorf("only published blogs can be archived, blog %d is %s", blog.Id, blog.BlogStatus)
+	}
+
+	return s.storage.UpdateBlogStatus(blog.Id, blog.BlogVersion, storage.BlogStatusArchived)
+}
+
+// DeleteBlog deletes a blog with any status along with its comments, likes and bookmarks
+func (s *Script) DeleteBlog(blogId int) (*storage.Blog, error) {
+
+	blog, err := s.getBlog(blogId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.DeleteBlogById(blog.Id, blog.BlogVersion); err != nil {
+		return nil, err
+	}
+
+	return blog, nil
+}
+
+func (s *Script) getBlog(blogId int) (*storage.Blog, error) {
+
+	blog, err := s.storage.GetBlogById(blogId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("no blog with id %d", blogId)
+		}
+		return nil, err
+	}
+
+	return blog, nil
+}
@@ -0,0 +1,67 @@
+package scripts
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/dhruv15803/go-blog-app/internal/storage"
+	"strings"
+)
+
+const EXPORT_TOPICS_PAGE_SIZE = 100
+
+// ExportTopics every topic, newest first
+func (s *Script) ExportTopics() ([]storage.Topic, error) {
+
+	var topics []storage.Topic
+
+	for page := 1; ; page++ {
+
+		pageTopics, err := s.storage.GetTopics(page*EXPORT_TOPICS_PAGE_SIZE-EXPORT_TOPICS_PAGE_SIZE, EXPORT_TOPICS_PAGE_SIZE)
+		if err != nil {
+			return nil, err
+		}
+
+		topics = append(topics, pageTopics...)
+
+		if len(pageTopics) < EXPORT_TOPICS_PAGE_SIZE {
+			break
+		}
+	}
+
+	return topics, nil
+}
+
+// ImportTopics creates the topics that do not exist yet, names are normalised like CreateTopicHandler does.
+// returns the created topics and the names that were skipped because they already exist
+func (s *Script) ImportTopics(topicNames []string) ([]storage.Topic, []string, error) {
+
+	var createdTopics []storage.Topic
+	var skippedTopicNames []string
+
+	for _, name := range topicNames {
+
+		topicName := strings.ToLower(strings.TrimSpace(name))
+
+		if topicName == "" {
+			continue
+		}
+
+		_, err := s.storage.GetTopicByTopicName(topicName)
+		if err == nil {
+			skippedTopicNames = append(skippedTopicNames, topicName)
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return createdTopics, skippedTopicNames, err
+		}
+
+		topic, err := s.storage.CreateTopic(topicName)
+		if err != nil {
+			return createdTopics, skippedTopicNames, err
+		}
+
+		createdTopics = append(createdTopics, *topic)
+	}
+
+	return createdTopics, skippedTopicNames, nil
+}
@@ -1,7 +1,9 @@
 package scripts
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/dhruv15803/go-blog-app/internal/storage"
 	"github.com/dhruv15803/go-blog-app/utils"
 	"golang.org/x/crypto/bcrypt"
@@ -51,3 +53,126 @@ func (s *Script) CreateVerifiedUser(email string, password string) (*storage.Use
 
 	return user, nil
 }
+
+// ListUsers the same filters as GET /api/admin/users, returns the page of users and the total count
+func (s *Script) ListUsers(searchText string, role *storage.UserRole, isVerified *bool, isSuspended *bool, page int, limit int) ([]storage.UserWithAccountState, int, error) {
+
+	if page < 1 || limit < 1 {
+		return nil, -1, errors.New("page and limit should be positive")
+	}
+
+	if role != nil && !IsValidRole(*role) {
+		return nil, -1, errors.New("invalid role, use one of user, admin, moderator, editor")
+	}
+
+	skip := page*limit - limit
+
+	users, err := s.storage.GetUsers(strings.TrimSpace(searchText), role, isVerified, isSuspended, skip, limit)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	totalCount, err := s.storage.GetUsersCount(strings.TrimSpace(searchText), role, isVerified, isSuspended)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	return users, totalCount, nil
+}
+
+// SetUserRole promotes or demotes the verified user with the email
+func (s *Script) SetUserRole(email string, role storage.UserRole) (*storage.User, error) {
+
+	if !IsValidRole(role) {
+		return nil, errors.New("invalid role, use one of user, admin, moderator, editor")
+	}
+
+	user, err := s.getVerifiedUser(email)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storage.UpdateUserRole(user.Id, role)
+}
+
+// DisableUser bans the verified user with the email (a suspension without an end)
+func (s *Script) DisableUser(email string, reason string) (*storage.User, error) {
+
+	user, err := s.getVerifiedUser(email)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.SuspendUser(user.Id, nil, nil, strings.TrimSpace(reason)); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// EnableUser lifts a suspension or ban of the verified user with the email
+func (s *Script) EnableUser(email string) (*storage.User, error) {
+
+	user, err := s.getVerifiedUser(email)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.UnsuspendUser(user.Id); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ResetUserPassword password passed in is plain text, the user is logged out everywhere
+func (s *Script) ResetUserPassword(email string, password string) (*storage.User, error) {
+
+	userPassword := strings.TrimSpace(password)
+
+	if !utils.IsPasswordStrong(userPassword) {
+		return nil, errors.New("weak password")
+	}
+
+	user, err := s.getVerifiedUser(email)
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(userPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storage.SetUserPassword(user.Id, string(hashedPassword))
+}
+
+func (s *Script) getVerifiedUser(email string) (*storage.User, error) {
+
+	userEmail := strings.ToLower(strings.TrimSpace(email))
+
+	if userEmail == "" {
+		return nil, errors.New("email is empty")
+	}
+
+	user, err := s.storage.GetVerifiedUserByEmail(userEmail)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("no verified user with email %s", userEmail)
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// IsValidRole one of user, admin, moderator, editor
+func IsValidRole(role storage.UserRole) bool {
+
+	switch role {
+	case storage.RoleUser, storage.RoleAdmin, storage.RoleModerator, storage.RoleEditor:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP (RFC 6238) with the defaults authenticator apps expect: HMAC-SHA1, 6 digits, 30 second steps
+
+const (
+	TOTP_DIGITS      = 6
+	TOTP_PERIOD      = 30
+	TOTP_SECRET_SIZE = 20
+	TOTP_SKEW_STEPS  = 1 // codes from one step before / after are accepted for clock drift
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret random base32 encoded (unpadded) secret
+func GenerateTOTPSecret() (string, error) {
+
+	secret := make([]byte, TOTP_SECRET_SIZE)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPAuthURI otpauth:// uri that authenticator apps import (usually rendered as a qr code)
+func TOTPAuthURI(secret string, issuer string, accountName string) string {
+
+	label := url.PathEscape(issuer + ":" + accountName)
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(TOTP_DIGITS))
+	params.Set("period", fmt.Sprint(TOTP_PERIOD))
+
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, params.Encode())
+}
+
+// TOTPStep the time step a code for t belongs to
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / TOTP_PERIOD
+}
+
+// TOTPCode the code of a secret for a time step (RFC 4226 HOTP with the step as counter)
+func TOTPCode(secret string, step int64) (string, error) {
+
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	truncated := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", TOTP_DIGITS, truncated%1000000), nil
+}
+
+// ValidateTOTPCode returns the matched time step so callers can reject a code that was already used
+func ValidateTOTPCode(secret string, code string, now time.Time) (int64, bool) {
+
+	if len(code) != TOTP_DIGITS {
+		return -1, false
+	}
+
+	currentStep := TOTPStep(now)
+
+	for step := currentStep - TOTP_SKEW_STEPS; step <= currentStep+TOTP_SKEW_STEPS; step++ {
+		expectedCode, err := TOTPCode(secret, step)
+		if err != nil {
+			return -1, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expectedCode), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return -1, false
+}
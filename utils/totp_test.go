@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// the RFC 6238 appendix B SHA1 secret, "12345678901234567890" as ascii
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+
+	// appendix B codes are 8 digits, a 6 digit code is the same value mod 10^6 (its last 6 digits)
+	tests := []struct {
+		unixTime int64
+		code     string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, test := range tests {
+		code, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(test.unixTime, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", test.unixTime, err)
+		}
+		if code != test.code {
+			t.Errorf("TOTPCode(%d) = %s, want %s", test.unixTime, code, test.code)
+		}
+	}
+}
+
+func TestValidateTOTPCodeSkew(t *testing.T) {
+
+	now := time.Unix(1234567890, 0)
+	currentStep := TOTPStep(now)
+
+	tests := []struct {
+		name    string
+		step    int64
+		isValid bool
+	}{
+		{"two steps before", currentStep - 2, false},
+		{"one step before", currentStep - 1, true},
+		{"current step", currentStep, true},
+		{"one step after", currentStep + 1, true},
+		{"two steps after", currentStep + 2, false},
+	}
+
+	for _, test := range tests {
+		code, err := TOTPCode(rfc6238Secret, test.step)
+		if err != nil {
+			t.Fatalf("%s: TOTPCode: %v", test.name, err)
+		}
+
+		step, isValid := ValidateTOTPCode(rfc6238Secret, code, now)
+		if isValid != test.isValid {
+			t.Errorf("%s: valid = %v, want %v", test.name, isValid, test.isValid)
+		}
+		if isValid && step != test.step {
+			t.Errorf("%s: matched step %d, want %d", test.name, step, test.step)
+		}
+	}
+}
+
+func TestValidateTOTPCodeLength(t *testing.T) {
+
+	now := time.Unix(1234567890, 0)
+
+	// the 8 digit appendix B code for this time, only the last 6 digits are a valid code
+	if _, isValid := ValidateTOTPCode(rfc6238Secret, "89005924", now); isValid {
+		t.Error("8 digit code accepted")
+	}
+
+	if _, isValid := ValidateTOTPCode(rfc6238Secret, "", now); isValid {
+		t.Error("empty code accepted")
+	}
+}
@@ -76,3 +76,37 @@ func IsValidEmail(email string) bool {
 
 	return true
 }
+
+func IsValidUsername(username string) bool {
+	//	valid username characteristics:
+	//	1] length between 3 and 30
+	//	2] only lowercase letters, numbers, '_' and '.'
+	//	3] atleast one letter (so that a username is never mistaken for a user id)
+	//	4] does not start or end with '.'
+
+	const USERNAME_CHARS = "abcdefghijklmnopqrstuvwxyz0123456789_."
+	const LETTER_CHARS = "abcdefghijklmnopqrstuvwxyz"
+
+	usernameLength := utf8.RuneCountInString(username)
+	if usernameLength < 3 || usernameLength > 30 {
+		return false
+	}
+
+	if strings.HasPrefix(username, ".") || strings.HasSuffix(username, ".") {
+		return false
+	}
+
+	hasLetterChar := false
+
+	for _, c := range username {
+		if !strings.Contains(USERNAME_CHARS, string(c)) {
+			return false
+		}
+
+		if !hasLetterChar && strings.Contains(LETTER_CHARS, string(c)) {
+			hasLetterChar = true
+		}
+	}
+
+	return hasLetterChar
+}